@@ -0,0 +1,468 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrNoEndpoint = errors.New("no endpoint available")
+
+type Endpoint struct {
+	Host   string
+	Port   uint16
+	Weight int
+}
+
+func (e Endpoint) Address() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
+}
+
+type BalanceStrategy int
+
+const (
+	RoundRobin BalanceStrategy = iota
+	LeastInFlight
+	PowerOfTwoChoices
+	ConsistentHash
+)
+
+type endpointState struct {
+	Endpoint
+
+	inFlight int64
+
+	currentWeight       int
+	consecutiveFailures int
+	ejectedUntil        time.Time
+
+	health endpointHealth
+}
+
+func (s *endpointState) loadInFlight() int64 {
+	return atomic.LoadInt64(&s.inFlight)
+}
+
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []*endpointState
+	ring      []hashRingPoint
+
+	strategy BalanceStrategy
+
+	maxFailures  int
+	ejectionTime time.Duration
+
+	randIntN func(n int) int
+	now      func() time.Time
+
+	// removed is called with the addresses of the endpoints setEndpoints drops
+	removed func(addresses []string)
+}
+
+type hashRingPoint struct {
+	hash     uint64
+	endpoint *endpointState
+}
+
+const (
+	defaultEndpointWeight = 1
+	hashRingReplicas      = 64
+)
+
+func newEndpointPool() *endpointPool {
+	return &endpointPool{
+		strategy: RoundRobin,
+		randIntN: rand.IntN,
+		now:      time.Now,
+	}
+}
+
+func (c *Client) endpointPool() *endpointPool {
+	if c.pool == nil {
+		c.pool = newEndpointPool()
+	}
+	return c.pool
+}
+
+// WithEndpoints spreads requests over several endpoints instead of Host and
+// Port. Requests keep the client host in their URL, so it is still sent as the
+// Host header, verified in TLS and used by cookies, only the connection is
+// made to the endpoint.
+func WithEndpoints(endpoints ...Endpoint) ClientOption {
+	return func(c *Client) error {
+		if len(endpoints) == 0 {
+			return errors.New("endpoints is empty")
+		}
+		for _, endpoint := range endpoints {
+			if endpoint.Weight < 0 {
+				return fmt.Errorf("invalid weight %d of endpoint %s", endpoint.Weight, endpoint.Address())
+			}
+		}
+		c.endpointPool().setEndpoints(endpoints)
+		return nil
+	}
+}
+
+func WithBalanceStrategy(strategy BalanceStrategy) ClientOption {
+	return func(c *Client) error {
+		if strategy < RoundRobin || strategy > ConsistentHash {
+			return fmt.Errorf("unsupported balance strategy %d", strategy)
+		}
+		c.endpointPool().strategy = strategy
+		return nil
+	}
+}
+
+// WithOutlierEjection stops sending requests to an endpoint for ejectionTime
+// after consecutiveFailures connection errors or 5xx responses in a row.
+func WithOutlierEjection(consecutiveFailures int, ejectionTime time.Duration) ClientOption {
+	return func(c *Client) error {
+		if consecutiveFailures <= 0 {
+			return fmt.Errorf("invalid consecutive failures %d", consecutiveFailures)
+		}
+		pool := c.endpointPool()
+		pool.maxFailures = consecutiveFailures
+		pool.ejectionTime = ejectionTime
+		return nil
+	}
+}
+
+func (p *endpointPool) setEndpoints(endpoints []Endpoint) {
+	p.mu.Lock()
+
+	existing := make(map[string]*endpointState, len(p.endpoints))
+	for _, state := range p.endpoints {
+		existing[state.Address()] = state
+	}
+
+	states := make([]*endpointState, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Weight == 0 {
+			endpoint.Weight = defaultEndpointWeight
+		}
+		state, ok := existing[endpoint.Address()]
+		if !ok {
+			state = &endpointState{}
+		}
+		state.Endpoint = endpoint
+		states = append(states, state)
+		delete(existing, endpoint.Address())
+	}
+
+	p.endpoints = states
+	p.ring = buildHashRing(states)
+	removed := p.removed
+	p.mu.Unlock()
+
+	if removed != nil && len(existing) != 0 {
+		addresses := make([]string, 0, len(existing))
+		for address := range existing {
+			addresses = append(addresses, address)
+		}
+		removed(addresses)
+	}
+}
+
+func (p *endpointPool) list() []Endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	endpoints := make([]Endpoint, 0, len(p.endpoints))
+	for _, state := range p.endpoints {
+		endpoints = append(endpoints, state.Endpoint)
+	}
+	return endpoints
+}
+
+func (p *endpointPool) pick(key string, exclude map[*endpointState]bool) (*endpointState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	candidates := p.candidates(exclude)
+	if len(candidates) == 0 {
+		return nil, ErrNoEndpoint
+	}
+
+	var picked *endpointState
+	switch p.strategy {
+	case LeastInFlight:
+		picked = pickLeastInFlight(candidates)
+	case PowerOfTwoChoices:
+		first, second := p.pickWeightedRandom(candidates), p.pickWeightedRandom(candidates)
+		picked = pickLeastInFlight([]*endpointState{first, second})
+	case ConsistentHash:
+		if key != "" {
+			picked = p.pickHashRing(key, candidates)
+		}
+	}
+	if picked == nil {
+		picked = pickSmoothWeighted(candidates)
+	}
+
+	atomic.AddInt64(&picked.inFlight, 1)
+
+	return picked, nil
+}
+
+// candidates returns the endpoints not excluded, preferring healthy ones which
+// are not ejected. When there is none, all of them are used rather than failing.
+func (p *endpointPool) candidates(exclude map[*endpointState]bool) []*endpointState {
+	now := p.now()
+
+	var available, unavailable []*endpointState
+	for _, state := range p.endpoints {
+		if exclude[state] {
+			continue
+		}
+		if state.health.unhealthy || state.ejectedUntil.After(now) {
+			unavailable = append(unavailable, state)
+			continue
+		}
+		available = append(available, state)
+	}
+
+	if len(available) == 0 {
+		return unavailable
+	}
+	return available
+}
+
+func (p *endpointPool) done(state *endpointState, failed bool) {
+	atomic.AddInt64(&state.inFlight, -1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !failed {
+		state.consecutiveFailures = 0
+		return
+	}
+
+	state.consecutiveFailures++
+	if p.maxFailures > 0 && state.consecutiveFailures >= p.maxFailures {
+		state.ejectedUntil = p.now().Add(p.ejectionTime)
+		state.consecutiveFailures = 0
+	}
+}
+
+func pickSmoothWeighted(candidates []*endpointState) *endpointState {
+	var picked *endpointState
+	total := 0
+	for _, state := range candidates {
+		state.currentWeight += state.Weight
+		total += state.Weight
+		if picked == nil || state.currentWeight > picked.currentWeight {
+			picked = state
+		}
+	}
+	picked.currentWeight -= total
+	return picked
+}
+
+func pickLeastInFlight(candidates []*endpointState) *endpointState {
+	var picked *endpointState
+	for _, state := range candidates {
+		if picked == nil {
+			picked = state
+			continue
+		}
+		// compare inFlight/Weight without dividing
+		if state.loadInFlight()*int64(picked.Weight) < picked.loadInFlight()*int64(state.Weight) {
+			picked = state
+		}
+	}
+	return picked
+}
+
+func (p *endpointPool) pickWeightedRandom(candidates []*endpointState) *endpointState {
+	total := 0
+	for _, state := range candidates {
+		total += state.Weight
+	}
+
+	n := p.randIntN(total)
+	for _, state := range candidates {
+		n -= state.Weight
+		if n < 0 {
+			return state
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func (p *endpointPool) pickHashRing(key string, candidates []*endpointState) *endpointState {
+	if len(p.ring) == 0 {
+		return nil
+	}
+
+	allowed := make(map[*endpointState]bool, len(candidates))
+	for _, state := range candidates {
+		allowed[state] = true
+	}
+
+	hash := hashKey(key)
+	start := sort.Search(len(p.ring), func(i int) bool {
+		return p.ring[i].hash >= hash
+	})
+	for i := 0; i < len(p.ring); i++ {
+		point := p.ring[(start+i)%len(p.ring)]
+		if allowed[point.endpoint] {
+			return point.endpoint
+		}
+	}
+	return nil
+}
+
+func buildHashRing(states []*endpointState) []hashRingPoint {
+	var ring []hashRingPoint
+	for _, state := range states {
+		for i := 0; i < state.Weight*hashRingReplicas; i++ {
+			ring = append(ring, hashRingPoint{
+				hash:     hashKey(state.Address() + "#" + strconv.Itoa(i)),
+				endpoint: state,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, k int) bool {
+		return ring[i].hash < ring[k].hash
+	})
+	return ring
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+func (c *Client) doWithEndpoints(req *Request, httpRequest *http.Request) (*Response, error) {
+	tried := map[*endpointState]bool{}
+	var lastErr error
+
+	for {
+		state, err := c.pool.pick(req.BalanceKey, tried)
+		if err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+		tried[state] = true
+
+		attempt := httpRequest.Clone(context.WithValue(httpRequest.Context(), endpointKey{}, state.Address()))
+		if len(tried) > 1 && httpRequest.Body != nil && httpRequest.Body != http.NoBody {
+			if httpRequest.GetBody == nil {
+				c.pool.done(state, false)
+				return nil, lastErr
+			}
+			attempt.Body, err = httpRequest.GetBody()
+			if err != nil {
+				c.pool.done(state, false)
+				return nil, err
+			}
+		}
+
+		resp, err := c.send(attempt)
+		c.pool.done(state, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+		if err != nil && isConnectionError(err) {
+			lastErr = err
+			continue
+		}
+
+		return resp, err
+	}
+}
+
+type endpointKey struct{}
+
+// endpointTransport sends requests to the endpoint picked for them. It keeps a
+// transport per endpoint, as transports pool connections by URL host.
+type endpointTransport struct {
+	transport *http.Transport
+	target    string
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func newEndpointTransport(transport *http.Transport, target string) *endpointTransport {
+	return &endpointTransport{
+		transport:  transport,
+		target:     target,
+		transports: map[string]*http.Transport{},
+	}
+}
+
+func (t *endpointTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	address, ok := httpRequest.Context().Value(endpointKey{}).(string)
+	if !ok || httpRequest.URL.Host != t.target {
+		return t.transport.RoundTrip(httpRequest)
+	}
+	return t.endpoint(address).RoundTrip(httpRequest)
+}
+
+// endpoint returns the transport dialing address instead of the client host.
+func (t *endpointTransport) endpoint(address string) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	transport, ok := t.transports[address]
+	if ok {
+		return transport
+	}
+
+	transport = t.transport.Clone()
+	dial := transport.DialContext
+	if dial == nil {
+		var dialer net.Dialer
+		dial = dialer.DialContext
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == t.target {
+			addr = address
+		}
+		return dial(ctx, network, addr)
+	}
+	t.transports[address] = transport
+	return transport
+}
+
+// remove closes the idle connections to addresses and forgets their
+// transports.
+func (t *endpointTransport) remove(addresses []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, address := range addresses {
+		if transport, ok := t.transports[address]; ok {
+			transport.CloseIdleConnections()
+			delete(t.transports, address)
+		}
+	}
+}
+
+func (t *endpointTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, transport := range t.transports {
+		transport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,331 @@
+package request
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestEndpoint(server *httptest.Server, weight int) Endpoint {
+	serverURL, _ := url.Parse(server.URL)
+	port, _ := strconv.ParseUint(serverURL.Port(), 10, 16)
+	return Endpoint{Host: serverURL.Hostname(), Port: uint16(port), Weight: weight}
+}
+
+func newClosedEndpoint(t *testing.T) Endpoint {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	_ = listener.Close()
+	return Endpoint{Host: "127.0.0.1", Port: uint16(addr.Port)}
+}
+
+func pickAddresses(pool *endpointPool, key string, count int) (addresses []string) {
+	for i := 0; i < count; i++ {
+		state, err := pool.pick(key, nil)
+		if err != nil {
+			return
+		}
+		addresses = append(addresses, state.Host)
+		pool.done(state, false)
+	}
+	return
+}
+
+func Test_endpointPool_pick(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  BalanceStrategy
+		endpoints []Endpoint
+		inFlight  []int64
+		randIntN  func(n int) int
+		key       string
+		want      []string
+	}{
+		{
+			name:     "round robin",
+			strategy: RoundRobin,
+			endpoints: []Endpoint{
+				{Host: "a", Port: 80},
+				{Host: "b", Port: 80},
+			},
+			want: []string{"a", "b", "a", "b"},
+		},
+		{
+			name:     "weighted round robin",
+			strategy: RoundRobin,
+			endpoints: []Endpoint{
+				{Host: "a", Port: 80, Weight: 2},
+				{Host: "b", Port: 80, Weight: 1},
+			},
+			want: []string{"a", "b", "a", "a", "b", "a"},
+		},
+		{
+			name:     "least in flight",
+			strategy: LeastInFlight,
+			endpoints: []Endpoint{
+				{Host: "a", Port: 80},
+				{Host: "b", Port: 80},
+				{Host: "c", Port: 80},
+			},
+			inFlight: []int64{3, 1, 2},
+			want:     []string{"b", "b"},
+		},
+		{
+			name:     "power of two choices",
+			strategy: PowerOfTwoChoices,
+			endpoints: []Endpoint{
+				{Host: "a", Port: 80},
+				{Host: "b", Port: 80},
+				{Host: "c", Port: 80},
+			},
+			inFlight: []int64{5, 1, 0},
+			randIntN: func(n int) int { return 0 },
+			want:     []string{"a", "a"},
+		},
+		{
+			name:     "consistent hash",
+			strategy: ConsistentHash,
+			endpoints: []Endpoint{
+				{Host: "a", Port: 80},
+				{Host: "b", Port: 80},
+				{Host: "c", Port: 80},
+			},
+			key:  "user-1",
+			want: []string{"c", "c", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newEndpointPool()
+			pool.strategy = tt.strategy
+			pool.setEndpoints(tt.endpoints)
+			for i, inFlight := range tt.inFlight {
+				pool.endpoints[i].inFlight = inFlight
+			}
+			if tt.randIntN != nil {
+				pool.randIntN = tt.randIntN
+			}
+
+			if got := pickAddresses(pool, tt.key, len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pick() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_endpointPool_consistentHashFailover(t *testing.T) {
+	pool := newEndpointPool()
+	pool.strategy = ConsistentHash
+	pool.setEndpoints([]Endpoint{{Host: "a", Port: 80}, {Host: "b", Port: 80}, {Host: "c", Port: 80}})
+
+	first, _ := pool.pick("user-1", nil)
+	second, _ := pool.pick("user-1", map[*endpointState]bool{first: true})
+	if second == first {
+		t.Errorf("pick() = %v, want another endpoint than %v", second.Host, first.Host)
+	}
+}
+
+func Test_endpointPool_done(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name      string
+		failures  []bool
+		elapsed   time.Duration
+		want      []string
+		wantFirst string
+	}{
+		{
+			name:     "eject after consecutive failures",
+			failures: []bool{true, true},
+			want:     []string{"b", "b"},
+		},
+		{
+			name:     "success resets failures",
+			failures: []bool{true, false, true},
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "return after ejection time",
+			failures: []bool{true, true},
+			elapsed:  time.Minute,
+			want:     []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newEndpointPool()
+			pool.maxFailures = 2
+			pool.ejectionTime = 30 * time.Second
+			pool.now = func() time.Time { return now }
+			pool.setEndpoints([]Endpoint{{Host: "a", Port: 80}, {Host: "b", Port: 80}})
+
+			a := pool.endpoints[0]
+			for _, failed := range tt.failures {
+				a.inFlight++
+				pool.done(a, failed)
+			}
+			pool.now = func() time.Time { return now.Add(tt.elapsed) }
+
+			if got := pickAddresses(pool, "", len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pick() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_endpointPool_allEjected(t *testing.T) {
+	pool := newEndpointPool()
+	pool.maxFailures = 1
+	pool.ejectionTime = time.Minute
+	pool.setEndpoints([]Endpoint{{Host: "a", Port: 80}})
+	pool.done(pool.endpoints[0], true)
+
+	if got := pickAddresses(pool, "", 1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("pick() = %v, want %v", got, []string{"a"})
+	}
+}
+
+func TestWithEndpoints(t *testing.T) {
+	hits := map[string]int{}
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[name]++
+			w.Header().Set("X-Host", r.Host)
+		}))
+	}
+	serverA, serverB := newServer("a"), newServer("b")
+	defer serverA.Close()
+	defer serverB.Close()
+
+	client, err := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithPort(80),
+		WithEndpoints(newClosedEndpoint(t), newTestEndpoint(serverA, 1), newTestEndpoint(serverB, 1)),
+		WithOutlierEjection(1, time.Minute),
+	)
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+
+	for i := 0; i < 4; i++ {
+		req, _ := NewRequest(http.MethodPost, "/api/test", WithBodyParams(NewJsonBodyParams(map[string]int{"i": i})))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Do() error = %v", err)
+			return
+		}
+		if got := resp.Header.Get("X-Host"); got != "api.example.com:80" {
+			t.Errorf("Do() Host = %v, want %v", got, "api.example.com:80")
+		}
+	}
+
+	if hits["a"] != 2 || hits["b"] != 2 {
+		t.Errorf("Do() hits = %v, want 2 for each endpoint", hits)
+	}
+}
+
+func TestWithEndpoints_allDown(t *testing.T) {
+	client, _ := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newClosedEndpoint(t), newClosedEndpoint(t)),
+	)
+	req, _ := NewRequest(http.MethodGet, "/api/test")
+
+	_, err := client.Do(req)
+	if !isConnectionError(err) {
+		t.Errorf("Do() error = %v, want connection error", err)
+	}
+}
+
+func TestWithEndpoints_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ClientOption
+	}{
+		{name: "empty endpoints", options: []ClientOption{WithEndpoints()}},
+		{name: "negative weight", options: []ClientOption{WithEndpoints(Endpoint{Host: "a", Weight: -1})}},
+		{name: "invalid strategy", options: []ClientOption{WithBalanceStrategy(BalanceStrategy(99))}},
+		{name: "invalid ejection", options: []ClientOption{WithOutlierEjection(0, time.Minute)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient("127.0.0.1", tt.options...)
+			if err == nil {
+				t.Errorf("NewClient() error = %v, wantErr true", err)
+			}
+		})
+	}
+}
+
+func TestWithEndpoints_logicalHost(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Server", name)
+			if cookie, err := r.Cookie("session"); err == nil {
+				w.Header().Set("X-Session", cookie.Value)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "token"})
+		}))
+	}
+	serverA, serverB := newServer("a"), newServer("b")
+	defer serverA.Close()
+	defer serverB.Close()
+
+	jar, _ := NewCookieJar()
+	client, err := NewClient(
+		// the certificate of httptest servers is valid for example.com
+		"example.com",
+		WithTransport(serverA.Client().Transport.(*http.Transport).Clone()),
+		WithEndpoints(newTestEndpoint(serverA, 1), newTestEndpoint(serverB, 1)),
+		WithCookieJar(jar),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	servers := map[string]string{}
+	for i := 0; i < 2; i++ {
+		req, _ := NewRequest(http.MethodGet, "/api/test")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		servers[resp.Header.Get("X-Server")] = resp.Header.Get("X-Session")
+	}
+
+	want := map[string]string{"a": "", "b": "token"}
+	if !reflect.DeepEqual(servers, want) {
+		t.Errorf("Do() sessions = %v, want %v", servers, want)
+	}
+}
+
+func Test_endpointTransport_remove(t *testing.T) {
+	pool := newEndpointPool()
+	transport := newEndpointTransport(&http.Transport{}, "api.example.com:80")
+	pool.removed = transport.remove
+	pool.setEndpoints([]Endpoint{{Host: "a", Port: 80}, {Host: "b", Port: 80}})
+	transport.endpoint("a:80")
+	transport.endpoint("b:80")
+
+	pool.setEndpoints([]Endpoint{{Host: "b", Port: 80}, {Host: "c", Port: 80}})
+
+	got := make([]string, 0, len(transport.transports))
+	for address := range transport.transports {
+		got = append(got, address)
+	}
+	if want := []string{"b:80"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transports = %v, want %v", got, want)
+	}
+}
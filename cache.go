@@ -0,0 +1,316 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CacheStatus string
+
+const (
+	CacheMiss        CacheStatus = "miss"
+	CacheHit         CacheStatus = "hit"
+	CacheRevalidated CacheStatus = "revalidated"
+)
+
+const (
+	cacheControlHeader    = "Cache-Control"
+	expiresHeader         = "Expires"
+	dateHeader            = "Date"
+	ageHeader             = "Age"
+	varyHeader            = "Vary"
+	etagHeader            = "ETag"
+	lastModifiedHeader    = "Last-Modified"
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+)
+
+// heuristicCacheableStatus are the status codes which may be cached without
+// explicit freshness, see RFC 9110 section 15.1.
+var heuristicCacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+type httpCache struct {
+	storage CacheStorage
+
+	now func() time.Time
+}
+
+// WithCache caches GET responses following RFC 9111 as a private cache, a nil
+// storage keeps the entries in memory. A response keeps one entry per URL, a
+// request with other values of the Vary headers replaces it.
+func WithCache(storage CacheStorage) ClientOption {
+	return func(c *Client) error {
+		if storage == nil {
+			storage = NewMemoryCacheStorage(defaultMemoryCacheEntries)
+		}
+		c.cache = &httpCache{
+			storage: storage,
+			now:     time.Now,
+		}
+		return nil
+	}
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, value := range header.Values(cacheControlHeader) {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	arg, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func cacheKey(httpRequest *http.Request) string {
+	return httpRequest.Method + " " + httpRequest.URL.String()
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return true
+}
+
+func (c *httpCache) do(httpRequest *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	if httpRequest.Header.Get(ifNoneMatchHeader) != "" || httpRequest.Header.Get(ifModifiedSinceHeader) != "" {
+		// a conditional request of the caller wants the answer of the server
+		return send(httpRequest)
+	}
+
+	if httpRequest.Method != http.MethodGet {
+		resp, err := send(httpRequest)
+		if err == nil && isUnsafeMethod(httpRequest.Method) && resp.StatusCode < http.StatusBadRequest {
+			// RFC 9111 section 4.4, the stored response may be changed by the request
+			get := httpRequest.Clone(httpRequest.Context())
+			get.Method = http.MethodGet
+			_ = c.storage.Delete(cacheKey(get))
+		}
+		return resp, err
+	}
+
+	key := cacheKey(httpRequest)
+	requestCC := parseCacheControl(httpRequest.Header)
+
+	entry, ok := c.storage.Get(key)
+	if ok && (requestCC.has("no-store") || !entry.matchVary(httpRequest)) {
+		ok = false
+	}
+
+	if ok && !requestCC.has("no-cache") && c.fresh(entry, requestCC) {
+		resp := entry.response()
+		resp.CacheStatus = CacheHit
+		return resp, nil
+	}
+
+	sendRequest := httpRequest
+	if ok {
+		sendRequest = entry.conditionalRequest(httpRequest)
+	}
+
+	requestTime := c.now()
+	resp, err := send(sendRequest)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := c.now()
+
+	if ok && sendRequest != httpRequest && resp.StatusCode == http.StatusNotModified {
+		entry = entry.clone()
+		entry.update(resp.Header)
+		entry.RequestTime, entry.ResponseTime = requestTime, responseTime
+		_ = c.storage.Set(key, entry)
+
+		cached := entry.response()
+		cached.CacheStatus = CacheRevalidated
+		return cached, nil
+	}
+
+	resp.CacheStatus = CacheMiss
+	if !requestCC.has("no-store") && storable(resp) {
+		// caching is best effort, a storage error does not fail the request
+		_ = c.storage.Set(key, newCacheEntry(httpRequest, resp, requestTime, responseTime))
+	} else if ok {
+		_ = c.storage.Delete(key)
+	}
+	return resp, nil
+}
+
+func storable(resp *Response) bool {
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || resp.Header.Get(varyHeader) == "*" {
+		return false
+	}
+	if heuristicCacheableStatus[resp.StatusCode] {
+		return true
+	}
+	_, hasMaxAge := cc.seconds("max-age")
+	return hasMaxAge || resp.Header.Get(expiresHeader) != "" || cc.has("public") || cc.has("private")
+}
+
+func newCacheEntry(httpRequest *http.Request, resp *Response, requestTime, responseTime time.Time) *CacheEntry {
+	entry := &CacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       append([]byte(nil), resp.RawBody...),
+
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+
+	for _, name := range varyNames(resp.Header) {
+		if entry.VaryHeader == nil {
+			entry.VaryHeader = http.Header{}
+		}
+		for _, value := range httpRequest.Header.Values(name) {
+			entry.VaryHeader.Add(name, value)
+		}
+	}
+	return entry
+}
+
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values(varyHeader) {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+func (e *CacheEntry) matchVary(httpRequest *http.Request) bool {
+	for _, name := range varyNames(e.Header) {
+		if strings.Join(httpRequest.Header.Values(name), ", ") != strings.Join(e.VaryHeader.Values(name), ", ") {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *CacheEntry) response() *Response {
+	return &Response{
+		StatusCode: e.StatusCode,
+		Header:     e.Header.Clone(),
+		RawBody:    append([]byte(nil), e.Body...),
+	}
+}
+
+// conditionalRequest asks the server to validate the entry, the request is
+// returned unchanged when the entry has no validator.
+func (e *CacheEntry) conditionalRequest(httpRequest *http.Request) *http.Request {
+	etag := e.Header.Get(etagHeader)
+	lastModified := e.Header.Get(lastModifiedHeader)
+	if etag == "" && lastModified == "" {
+		return httpRequest
+	}
+
+	conditional := httpRequest.Clone(httpRequest.Context())
+	if etag != "" {
+		conditional.Header.Set(ifNoneMatchHeader, etag)
+	}
+	if lastModified != "" {
+		conditional.Header.Set(ifModifiedSinceHeader, lastModified)
+	}
+	return conditional
+}
+
+// update merges the header of a 304 response, see RFC 9111 section 3.2.
+func (e *CacheEntry) update(header http.Header) {
+	for name, values := range header {
+		if name == "Content-Length" {
+			continue
+		}
+		e.Header[name] = append([]string(nil), values...)
+	}
+}
+
+// fresh compares the freshness lifetime of the entry to its current age, see
+// RFC 9111 section 4.2.
+func (c *httpCache) fresh(e *CacheEntry, requestCC cacheControl) bool {
+	cc := parseCacheControl(e.Header)
+	if cc.has("no-cache") {
+		return false
+	}
+
+	date, err := http.ParseTime(e.Header.Get(dateHeader))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	var lifetime time.Duration
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		lifetime = maxAge
+	} else if expires := e.Header.Get(expiresHeader); expires != "" {
+		expiresTime, err := http.ParseTime(expires)
+		if err != nil {
+			return false
+		}
+		lifetime = expiresTime.Sub(date)
+	} else if lastModified, err := http.ParseTime(e.Header.Get(lastModifiedHeader)); err == nil &&
+		heuristicCacheableStatus[e.StatusCode] {
+		lifetime = date.Sub(lastModified) / 10
+	}
+
+	apparentAge := e.ResponseTime.Sub(date)
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	age := e.ResponseTime.Sub(e.RequestTime)
+	if ageValue, err := strconv.ParseInt(e.Header.Get(ageHeader), 10, 64); err == nil && ageValue > 0 {
+		age += time.Duration(ageValue) * time.Second
+	}
+	if apparentAge > age {
+		age = apparentAge
+	}
+	age += c.now().Sub(e.ResponseTime)
+
+	if maxAge, ok := requestCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := requestCC.seconds("min-fresh"); ok {
+		age += minFresh
+	}
+	return age < lifetime
+}
@@ -0,0 +1,175 @@
+package request
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheStorage stores the responses of a client cache by key. Entries passed
+// to and returned by a storage must not be modified.
+type CacheStorage interface {
+	Get(key string) (entry *CacheEntry, ok bool)
+	Set(key string, entry *CacheEntry) error
+	Delete(key string) error
+}
+
+type CacheEntry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+
+	// VaryHeader holds the request headers named by the Vary response header.
+	VaryHeader http.Header `json:"vary_header,omitempty"`
+
+	RequestTime  time.Time `json:"request_time"`
+	ResponseTime time.Time `json:"response_time"`
+}
+
+func (e *CacheEntry) clone() *CacheEntry {
+	clone := *e
+	clone.Header = e.Header.Clone()
+	clone.VaryHeader = e.VaryHeader.Clone()
+	return &clone
+}
+
+// MemoryCacheStorage keeps at most maxEntries entries in memory, the least
+// recently used entry is evicted first.
+type MemoryCacheStorage struct {
+	maxEntries int
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	eviction *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+const defaultMemoryCacheEntries = 1024
+
+func NewMemoryCacheStorage(maxEntries int) *MemoryCacheStorage {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryCacheEntries
+	}
+
+	return &MemoryCacheStorage{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		eviction:   list.New(),
+	}
+}
+
+func (s *MemoryCacheStorage) Get(key string) (*CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.eviction.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).entry, true
+}
+
+func (s *MemoryCacheStorage) Set(key string, entry *CacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*memoryCacheItem).entry = entry
+		s.eviction.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[key] = s.eviction.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for s.eviction.Len() > s.maxEntries {
+		oldest := s.eviction.Back()
+		s.eviction.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+	return nil
+}
+
+func (s *MemoryCacheStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.eviction.Remove(element)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+func (s *MemoryCacheStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.eviction.Len()
+}
+
+// DiskCacheStorage keeps every entry in a JSON file of dir named by the
+// SHA-256 of its key.
+type DiskCacheStorage struct {
+	dir string
+}
+
+func NewDiskCacheStorage(dir string) (*DiskCacheStorage, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create cache dir error %w", err)
+	}
+
+	return &DiskCacheStorage{dir: dir}, nil
+}
+
+func (s *DiskCacheStorage) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get treats a file which can not be read as a missing entry.
+func (s *DiskCacheStorage) Get(key string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(s.filename(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	err = json.Unmarshal(data, &entry)
+	if err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (s *DiskCacheStorage) Set(key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal cache entry error %w", err)
+	}
+
+	err = writeFileAtomic(s.filename(key), data, 0o600)
+	if err != nil {
+		return fmt.Errorf("write cache file error %w", err)
+	}
+	return nil
+}
+
+func (s *DiskCacheStorage) Delete(key string) error {
+	err := os.Remove(s.filename(key))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove cache file error %w", err)
+	}
+	return nil
+}
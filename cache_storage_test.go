@@ -0,0 +1,86 @@
+package request
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestCacheEntry(body string) *CacheEntry {
+	return &CacheEntry{
+		StatusCode:   http.StatusOK,
+		Header:       http.Header{"Etag": []string{`"` + body + `"`}},
+		Body:         []byte(body),
+		RequestTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ResponseTime: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+	}
+}
+
+func TestMemoryCacheStorage(t *testing.T) {
+	storage := NewMemoryCacheStorage(2)
+	_ = storage.Set("a", newTestCacheEntry("a"))
+	_ = storage.Set("b", newTestCacheEntry("b"))
+	storage.Get("a")
+	_ = storage.Set("c", newTestCacheEntry("c"))
+
+	tests := []struct {
+		name   string
+		key    string
+		wantOk bool
+	}{
+		{name: "recently used", key: "a", wantOk: true},
+		{name: "evicted", key: "b", wantOk: false},
+		{name: "added", key: "c", wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := storage.Get(tt.key)
+			if ok != tt.wantOk {
+				t.Errorf("Get() ok = %v, want %v", ok, tt.wantOk)
+				return
+			}
+			if ok && string(entry.Body) != tt.key {
+				t.Errorf("Get() Body = %s, want %s", entry.Body, tt.key)
+			}
+		})
+	}
+
+	_ = storage.Delete("a")
+	if got := storage.Len(); got != 1 {
+		t.Errorf("Len() = %v, want %v", got, 1)
+	}
+}
+
+func TestDiskCacheStorage(t *testing.T) {
+	storage, err := NewDiskCacheStorage(t.TempDir())
+	if err != nil {
+		t.Errorf("NewDiskCacheStorage() error = %v", err)
+		return
+	}
+
+	want := newTestCacheEntry("a")
+	want.VaryHeader = http.Header{"Accept": []string{"text/plain"}}
+	if err := storage.Set("GET http://127.0.0.1/a", want); err != nil {
+		t.Errorf("Set() error = %v", err)
+		return
+	}
+
+	got, ok := storage.Get("GET http://127.0.0.1/a")
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, %v, want %+v", got, ok, want)
+	}
+	if _, ok := storage.Get("GET http://127.0.0.1/b"); ok {
+		t.Errorf("Get() ok = true, want false")
+	}
+
+	if err := storage.Delete("GET http://127.0.0.1/a"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, ok := storage.Get("GET http://127.0.0.1/a"); ok {
+		t.Errorf("Get() ok = true after Delete, want false")
+	}
+	if err := storage.Delete("GET http://127.0.0.1/a"); err != nil {
+		t.Errorf("Delete() missing error = %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cacheStep struct {
+	method     string
+	headers    map[string]string
+	advance    time.Duration
+	wantStatus CacheStatus
+}
+
+func TestWithCache(t *testing.T) {
+	lastModified := time.Now().Add(-10 * time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name         string
+		header       map[string]string
+		steps        []cacheStep
+		wantRequests int32
+	}{
+		{
+			name:   "max-age hit",
+			header: map[string]string{"Cache-Control": "max-age=60"},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{advance: 30 * time.Second, wantStatus: CacheHit},
+			},
+			wantRequests: 1,
+		},
+		{
+			name:   "revalidate stale entry with etag",
+			header: map[string]string{"Cache-Control": "max-age=60", "ETag": `"v1"`},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{advance: 2 * time.Minute, wantStatus: CacheRevalidated},
+				{advance: 30 * time.Second, wantStatus: CacheHit},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "revalidate no-cache with last-modified",
+			header: map[string]string{"Cache-Control": "no-cache", "Last-Modified": lastModified},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{wantStatus: CacheRevalidated},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "heuristic freshness from last-modified",
+			header: map[string]string{"Last-Modified": lastModified},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{advance: 30 * time.Minute, wantStatus: CacheHit},
+				{advance: 2 * time.Hour, wantStatus: CacheRevalidated},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "expires",
+			header: map[string]string{"Expires": time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{wantStatus: CacheHit},
+				{advance: 2 * time.Minute, wantStatus: CacheMiss},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "no-store",
+			header: map[string]string{"Cache-Control": "no-store, max-age=60"},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{wantStatus: CacheMiss},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "private",
+			header: map[string]string{"Cache-Control": "private, max-age=60"},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{wantStatus: CacheHit},
+			},
+			wantRequests: 1,
+		},
+		{
+			name:   "ignore s-maxage",
+			header: map[string]string{"Cache-Control": "s-maxage=60"},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{wantStatus: CacheMiss},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "request no-cache",
+			header: map[string]string{"Cache-Control": "max-age=60"},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{headers: map[string]string{"Cache-Control": "no-cache"}, wantStatus: CacheMiss},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "vary",
+			header: map[string]string{"Cache-Control": "max-age=60", "Vary": "Accept"},
+			steps: []cacheStep{
+				{headers: map[string]string{"Accept": "text/plain"}, wantStatus: CacheMiss},
+				{headers: map[string]string{"Accept": "text/plain"}, wantStatus: CacheHit},
+				{headers: map[string]string{"Accept": "application/json"}, wantStatus: CacheMiss},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "invalidate by unsafe method",
+			header: map[string]string{"Cache-Control": "max-age=60"},
+			steps: []cacheStep{
+				{wantStatus: CacheMiss},
+				{method: http.MethodPost},
+				{wantStatus: CacheMiss},
+			},
+			wantRequests: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			var now atomic.Int64
+			now.Store(time.Now().UnixNano())
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.Header().Set("Date", time.Unix(0, now.Load()).UTC().Format(http.TimeFormat))
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				etag, modified := tt.header["ETag"], tt.header["Last-Modified"]
+				if (etag != "" && r.Header.Get("If-None-Match") == etag) ||
+					(modified != "" && r.Header.Get("If-Modified-Since") == modified) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = w.Write([]byte("cached"))
+			}))
+			defer server.Close()
+
+			client, err := newTestClient(server, WithCache(nil))
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+			client.cache.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+			for i, step := range tt.steps {
+				now.Add(int64(step.advance))
+				method := step.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				req, _ := NewRequest(method, "/api/test", WithHeaders(step.headers))
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Errorf("Do() error = %v", err)
+					return
+				}
+				if method != http.MethodGet {
+					continue
+				}
+				if resp.CacheStatus != step.wantStatus {
+					t.Errorf("Do() step %d CacheStatus = %v, want %v", i, resp.CacheStatus, step.wantStatus)
+				}
+				if resp.StatusCode != http.StatusOK || string(resp.RawBody) != "cached" {
+					t.Errorf("Do() step %d response = %v %s, want 200 cached", i, resp.StatusCode, resp.RawBody)
+				}
+			}
+
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("Do() requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWithCache_conditionalRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server, WithCache(nil))
+	req, _ := NewRequest(http.MethodGet, "/api/test")
+	_, _ = client.Do(req)
+
+	req, _ = NewRequest(http.MethodGet, "/api/test", WithHeaders(map[string]string{"If-None-Match": `"v1"`}))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Do() StatusCode = %v, want %v", resp.StatusCode, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,461 @@
+package request
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+var ErrInteractionNotFound = errors.New("cassette interaction not found")
+
+type CassetteMode int
+
+const (
+	// CassetteRecordMissing replays recorded interactions and records the
+	// requests that have none.
+	CassetteRecordMissing CassetteMode = iota
+	// CassetteReplay only replays, requests without a recorded interaction
+	// fail with ErrInteractionNotFound.
+	CassetteReplay
+	// CassetteRecord sends every request and records it, replacing what the
+	// cassette held.
+	CassetteRecord
+	// CassettePassthrough sends every request without recording.
+	CassettePassthrough
+)
+
+const cassetteVersion = 1
+
+// CassetteInteraction is a recorded request and its response.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+type CassetteResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+type cassetteFile struct {
+	Version      int                    `json:"version"`
+	Interactions []*CassetteInteraction `json:"interactions"`
+}
+
+// CassetteMatcher reports whether a recorded request matches request.
+type CassetteMatcher func(request, recorded *CassetteRequest) bool
+
+// CassetteScrubFunc changes an interaction before it is recorded.
+type CassetteScrubFunc func(interaction *CassetteInteraction)
+
+type CassetteOption func(*Cassette) error
+
+// Cassette records the traffic of the clients using it and replays it.
+type Cassette struct {
+	filename  string
+	mode      CassetteMode
+	matchers  []CassetteMatcher
+	scrubbers []CassetteScrubFunc
+
+	mu           sync.Mutex
+	interactions []*CassetteInteraction
+	used         []bool
+	changed      bool
+}
+
+// NewCassette loads the cassette from filename, a missing file results in an
+// empty cassette. Requests are matched by method, URL and query by default.
+// Files ending in .yaml or .yml are YAML, others JSON.
+func NewCassette(filename string, options ...CassetteOption) (cassette *Cassette, err error) {
+	cassette = &Cassette{
+		filename: filename,
+		mode:     CassetteRecordMissing,
+		matchers: []CassetteMatcher{MatchMethod, MatchURL, MatchQuery},
+	}
+
+	for _, option := range options {
+		err = option(cassette)
+		if err != nil {
+			return
+		}
+	}
+
+	if cassette.mode == CassetteRecord {
+		return
+	}
+
+	cassette.interactions, err = LoadCassette(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	cassette.used = make([]bool, len(cassette.interactions))
+
+	return
+}
+
+// LoadCassette reads the interactions of a JSON or YAML cassette file.
+func LoadCassette(filename string) ([]*CassetteInteraction, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette file error %w", err)
+	}
+
+	var file cassetteFile
+	if isYAMLCassette(filename) {
+		err = unmarshalCassetteYAML(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal cassette file error %w", err)
+	}
+	if file.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d", file.Version)
+	}
+
+	return file.Interactions, nil
+}
+
+func WithCassetteMode(mode CassetteMode) CassetteOption {
+	return func(c *Cassette) error {
+		if mode < CassetteRecordMissing || mode > CassettePassthrough {
+			return fmt.Errorf("invalid cassette mode %d", mode)
+		}
+		c.mode = mode
+		return nil
+	}
+}
+
+// WithCassetteMatchers replaces the matchers, a recorded request matches when
+// all of them match.
+func WithCassetteMatchers(matchers ...CassetteMatcher) CassetteOption {
+	return func(c *Cassette) error {
+		c.matchers = matchers
+		return nil
+	}
+}
+
+func WithCassetteScrubber(scrubber CassetteScrubFunc) CassetteOption {
+	return func(c *Cassette) error {
+		c.scrubbers = append(c.scrubbers, scrubber)
+		return nil
+	}
+}
+
+// WithCassetteScrubHeaders redacts the values of request and response headers.
+func WithCassetteScrubHeaders(headers ...string) CassetteOption {
+	return WithCassetteScrubber(func(interaction *CassetteInteraction) {
+		for _, header := range headers {
+			scrubHeader(interaction.Request.Headers, header)
+			scrubHeader(interaction.Response.Headers, header)
+		}
+	})
+}
+
+// WithCassetteScrubQuery redacts the values of query keys.
+func WithCassetteScrubQuery(keys ...string) CassetteOption {
+	return WithCassetteScrubber(func(interaction *CassetteInteraction) {
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil {
+			return
+		}
+		query := u.Query()
+		for _, key := range keys {
+			if _, ok := query[key]; ok {
+				query[key] = []string{redactedQueryValue}
+			}
+		}
+		u.RawQuery = query.Encode()
+		interaction.Request.URL = u.String()
+	})
+}
+
+func scrubHeader(header http.Header, name string) {
+	if values := header.Values(name); len(values) != 0 {
+		header[http.CanonicalHeaderKey(name)] = []string{redacted}
+	}
+}
+
+// WithCassette records and replays the traffic of the client with cassette.
+func WithCassette(cassette *Cassette) ClientOption {
+	return func(c *Client) error {
+		if cassette == nil {
+			return fmt.Errorf("cassette is nil")
+		}
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &cassetteTransport{cassette: cassette, next: next}
+		})
+		return nil
+	}
+}
+
+func MatchMethod(request, recorded *CassetteRequest) bool {
+	return request.Method == recorded.Method
+}
+
+// MatchURL matches the URL without its query.
+func MatchURL(request, recorded *CassetteRequest) bool {
+	requestURL, err := url.Parse(request.URL)
+	if err != nil {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	requestURL.RawQuery, recordedURL.RawQuery = "", ""
+	return requestURL.String() == recordedURL.String()
+}
+
+// MatchQuery matches the query regardless of the order of its keys.
+func MatchQuery(request, recorded *CassetteRequest) bool {
+	requestURL, err := url.Parse(request.URL)
+	if err != nil {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(requestURL.Query(), recordedURL.Query())
+}
+
+func MatchHeaders(headers ...string) CassetteMatcher {
+	return func(request, recorded *CassetteRequest) bool {
+		for _, header := range headers {
+			if !reflect.DeepEqual(request.Headers.Values(header), recorded.Headers.Values(header)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchBody matches the body, JSON bodies regardless of the order of their
+// keys.
+func MatchBody(request, recorded *CassetteRequest) bool {
+	requestBody, err := decodeCassetteBody(request.Body, request.BodyEncoding)
+	if err != nil {
+		return false
+	}
+	recordedBody, err := decodeCassetteBody(recorded.Body, recorded.BodyEncoding)
+	if err != nil {
+		return false
+	}
+	if bytes.Equal(requestBody, recordedBody) {
+		return true
+	}
+
+	var requestJSON, recordedJSON interface{}
+	if json.Unmarshal(requestBody, &requestJSON) != nil || json.Unmarshal(recordedBody, &recordedJSON) != nil {
+		return false
+	}
+	return reflect.DeepEqual(requestJSON, recordedJSON)
+}
+
+func encodeCassetteBody(body []byte) (text, encoding string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeCassetteBody(text, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(text), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	default:
+		return nil, fmt.Errorf("unsupported cassette body encoding %s", encoding)
+	}
+}
+
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Interactions returns the recorded interactions.
+func (c *Cassette) Interactions() []*CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*CassetteInteraction{}, c.interactions...)
+}
+
+// Save writes the cassette file atomically when something was recorded.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	if !c.changed {
+		c.mu.Unlock()
+		return nil
+	}
+	file := &cassetteFile{Version: cassetteVersion, Interactions: c.interactions}
+	var data []byte
+	var err error
+	if isYAMLCassette(c.filename) {
+		data = marshalCassetteYAML(file)
+	} else {
+		data, err = json.MarshalIndent(file, "", "  ")
+	}
+	c.changed = false
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal cassette error %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.filename), 0o755)
+	if err != nil {
+		return fmt.Errorf("create cassette dir error %w", err)
+	}
+
+	err = writeFileAtomic(c.filename, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("write cassette file error %w", err)
+	}
+	return nil
+}
+
+// find returns the first unused interaction matching request.
+func (c *Cassette) find(request *CassetteRequest) *CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.interactions {
+		if c.used[i] || !c.match(request, &interaction.Request) {
+			continue
+		}
+		c.used[i] = true
+		return interaction
+	}
+	return nil
+}
+
+// scrubbed returns request scrubbed like the recorded ones, so that they can
+// be matched.
+func (c *Cassette) scrubbed(request CassetteRequest) *CassetteRequest {
+	interaction := &CassetteInteraction{Request: request}
+	interaction.Request.Headers = request.Headers.Clone()
+	for _, scrubber := range c.scrubbers {
+		scrubber(interaction)
+	}
+	return &interaction.Request
+}
+
+func (c *Cassette) match(request, recorded *CassetteRequest) bool {
+	for _, matcher := range c.matchers {
+		if !matcher(request, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cassette) record(interaction *CassetteInteraction) {
+	for _, scrubber := range c.scrubbers {
+		scrubber(interaction)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+	c.changed = true
+}
+
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	if t.cassette.mode == CassettePassthrough {
+		return t.next.RoundTrip(httpRequest)
+	}
+
+	httpRequest = httpRequest.Clone(httpRequest.Context())
+	body, err := readRequestBody(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	request := CassetteRequest{
+		Method:  httpRequest.Method,
+		URL:     httpRequest.URL.String(),
+		Headers: httpRequest.Header.Clone(),
+	}
+	if httpRequest.Host != "" && httpRequest.Host != httpRequest.URL.Host {
+		request.Headers.Set("Host", httpRequest.Host)
+	}
+	request.Body, request.BodyEncoding = encodeCassetteBody(body)
+
+	if t.cassette.mode != CassetteRecord {
+		if interaction := t.cassette.find(t.cassette.scrubbed(request)); interaction != nil {
+			return interaction.Response.httpResponse(httpRequest)
+		}
+		if t.cassette.mode == CassetteReplay {
+			return nil, fmt.Errorf("%w for %s %s", ErrInteractionNotFound, request.Method, request.URL)
+		}
+	}
+
+	httpResponse, err := t.next.RoundTrip(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	_ = httpResponse.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpResponse.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := &CassetteInteraction{
+		Request: request,
+		Response: CassetteResponse{
+			StatusCode: httpResponse.StatusCode,
+			Headers:    httpResponse.Header.Clone(),
+		},
+	}
+	interaction.Request.Headers = request.Headers.Clone()
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeCassetteBody(responseBody)
+	t.cassette.record(interaction)
+
+	return httpResponse, nil
+}
+
+func (t *cassetteTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
+
+func (r *CassetteResponse) httpResponse(httpRequest *http.Request) (*http.Response, error) {
+	body, err := decodeCassetteBody(r.Body, r.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResponse := newHTTPResponse(r.StatusCode, r.Headers.Clone(), body)
+	httpResponse.Request = httpRequest
+	return httpResponse, nil
+}
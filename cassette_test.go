@@ -0,0 +1,340 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newCassetteTestServer(hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("X-Token", "server-secret")
+		w.Header().Set(contentTypeHeader, contentTypeJson)
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+}
+
+func doCassetteRequest(t *testing.T, client *Client, path string) (*Response, error) {
+	t.Helper()
+	req, err := NewRequest(
+		http.MethodGet, path,
+		WithHeaders(map[string]string{"X-Token": "client-secret"}),
+		WithQueryParams(NewQueryParams(map[string]string{"access_token": "secret", "page": "1"})),
+	)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	return client.Do(req)
+}
+
+func TestNewCassette(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "cassette.json")
+	_ = os.WriteFile(filename, []byte(`{"version":1,"interactions":[{"request":{"method":"GET","url":"http://a/"},"response":{"status_code":200}}]}`), 0o644)
+	yamlFilename := filepath.Join(dir, "cassette.yaml")
+	_ = os.WriteFile(yamlFilename, []byte("version: 1\ninteractions:\n  - request: {method: GET}\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "cassette.yml"), []byte("version: 1\ninteractions:\n- request:\n    method: GET\n    url: http://a/\n  response:\n    status_code: 200\n"), 0o644)
+	invalid := filepath.Join(dir, "invalid.json")
+	_ = os.WriteFile(invalid, []byte(`{"version":2}`), 0o644)
+
+	tests := []struct {
+		name             string
+		filename         string
+		options          []CassetteOption
+		wantInteractions int
+		wantErr          bool
+	}{
+		{
+			name:             "new cassette from file",
+			filename:         filename,
+			wantInteractions: 1,
+		},
+		{
+			name:             "new cassette from missing file",
+			filename:         filepath.Join(dir, "missing.json"),
+			wantInteractions: 0,
+		},
+		{
+			name:             "new cassette in record mode",
+			filename:         filename,
+			options:          []CassetteOption{WithCassetteMode(CassetteRecord)},
+			wantInteractions: 0,
+		},
+		{
+			name:     "new cassette with unsupported version",
+			filename: invalid,
+			wantErr:  true,
+		},
+		{
+			name:             "new cassette from yaml file",
+			filename:         filepath.Join(dir, "cassette.yml"),
+			wantInteractions: 1,
+		},
+		{
+			name:     "new cassette with unsupported yaml",
+			filename: yamlFilename,
+			wantErr:  true,
+		},
+		{
+			name:     "new cassette with invalid mode",
+			filename: filename,
+			options:  []CassetteOption{WithCassetteMode(CassetteMode(10))},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cassette, err := NewCassette(tt.filename, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCassette() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got := len(cassette.Interactions()); got != tt.wantInteractions {
+				t.Errorf("NewCassette() interactions = %v, want %v", got, tt.wantInteractions)
+			}
+		})
+	}
+}
+
+func TestWithCassette(t *testing.T) {
+	var hits atomic.Int32
+	server := newCassetteTestServer(&hits)
+	defer server.Close()
+	filename := filepath.Join(t.TempDir(), "cassettes", "api.json")
+
+	cassette, _ := NewCassette(filename, WithCassetteScrubHeaders("X-Token"), WithCassetteScrubQuery("access_token"))
+	client, _ := newTestClient(server, WithCassette(cassette))
+	resp, err := doCassetteRequest(t, client, "/first")
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.Header.Get("X-Token") != "server-secret" {
+		t.Errorf("Do() recorded response was scrubbed %v", resp.Header)
+	}
+	_, _ = doCassetteRequest(t, client, "/first")
+	if hits.Load() != 2 {
+		t.Fatalf("hits = %v, want 2", hits.Load())
+	}
+	err = cassette.Save()
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(filename)
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Save() wrote secrets %s", data)
+	}
+
+	tests := []struct {
+		name     string
+		mode     CassetteMode
+		paths    []string
+		wantHits int32
+		wantErr  error
+	}{
+		{
+			name:     "replay recorded interactions",
+			mode:     CassetteReplay,
+			paths:    []string{"/first", "/first"},
+			wantHits: 0,
+		},
+		{
+			name:     "replay used interactions",
+			mode:     CassetteReplay,
+			paths:    []string{"/first", "/first", "/first"},
+			wantHits: 0,
+			wantErr:  ErrInteractionNotFound,
+		},
+		{
+			name:     "replay missing interactions",
+			mode:     CassetteReplay,
+			paths:    []string{"/second"},
+			wantHits: 0,
+			wantErr:  ErrInteractionNotFound,
+		},
+		{
+			name:     "record missing interactions",
+			mode:     CassetteRecordMissing,
+			paths:    []string{"/first", "/second"},
+			wantHits: 1,
+		},
+		{
+			name:     "record interactions",
+			mode:     CassetteRecord,
+			paths:    []string{"/first"},
+			wantHits: 1,
+		},
+		{
+			name:     "pass interactions through",
+			mode:     CassettePassthrough,
+			paths:    []string{"/first"},
+			wantHits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			cassette, err := NewCassette(
+				filename,
+				WithCassetteMode(tt.mode),
+				WithCassetteScrubHeaders("X-Token"),
+				WithCassetteScrubQuery("access_token"),
+			)
+			if err != nil {
+				t.Fatalf("NewCassette() error = %v", err)
+			}
+			client, _ := newTestClient(server, WithCassette(cassette))
+
+			var gotErr error
+			for _, path := range tt.paths {
+				resp, err := doCassetteRequest(t, client, path)
+				if err != nil {
+					gotErr = err
+					break
+				}
+				if want := `{"path":"` + path + `"}`; string(resp.RawBody) != want {
+					t.Errorf("Do() body = %s, want %s", resp.RawBody, want)
+				}
+			}
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", gotErr, tt.wantErr)
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("hits = %v, want %v", hits.Load(), tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestWithCassette_yaml(t *testing.T) {
+	var hits atomic.Int32
+	server := newCassetteTestServer(&hits)
+	defer server.Close()
+	filename := filepath.Join(t.TempDir(), "api.yaml")
+
+	cassette, _ := NewCassette(filename, WithCassetteScrubHeaders("X-Token"), WithCassetteScrubQuery("access_token"))
+	client, _ := newTestClient(server, WithCassette(cassette))
+	_, _ = doCassetteRequest(t, client, "/first")
+	_, _ = doCassetteRequest(t, client, "/second")
+	err := cassette.Save()
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(filename)
+	if !strings.HasPrefix(string(data), "version: 1\ninteractions:\n  - request:\n") || strings.Contains(string(data), "secret") {
+		t.Errorf("Save() wrote %s", data)
+	}
+
+	hits.Store(0)
+	cassette, err = NewCassette(filename, WithCassetteMode(CassetteReplay), WithCassetteScrubQuery("access_token"))
+	if err != nil {
+		t.Fatalf("NewCassette() error = %v", err)
+	}
+	client, _ = newTestClient(server, WithCassette(cassette))
+	for _, path := range []string{"/first", "/second"} {
+		resp, err := doCassetteRequest(t, client, path)
+		if err != nil {
+			t.Errorf("Do() error = %v", err)
+			continue
+		}
+		if want := `{"path":"` + path + `"}`; string(resp.RawBody) != want {
+			t.Errorf("Do() body = %s, want %s", resp.RawBody, want)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Errorf("hits = %v, want 0", hits.Load())
+	}
+}
+
+func TestCassetteMatchers(t *testing.T) {
+	recorded := &CassetteRequest{
+		Method:  http.MethodPost,
+		URL:     "http://example.com/api?a=1&b=2",
+		Headers: http.Header{"X-Version": []string{"1"}},
+		Body:    `{"a":1,"b":2}`,
+	}
+	tests := []struct {
+		name    string
+		matcher CassetteMatcher
+		request *CassetteRequest
+		want    bool
+	}{
+		{
+			name:    "match method",
+			matcher: MatchMethod,
+			request: &CassetteRequest{Method: http.MethodPost},
+			want:    true,
+		},
+		{
+			name:    "mismatch method",
+			matcher: MatchMethod,
+			request: &CassetteRequest{Method: http.MethodGet},
+			want:    false,
+		},
+		{
+			name:    "match url without query",
+			matcher: MatchURL,
+			request: &CassetteRequest{URL: "http://example.com/api?c=3"},
+			want:    true,
+		},
+		{
+			name:    "mismatch url",
+			matcher: MatchURL,
+			request: &CassetteRequest{URL: "http://example.com/other?a=1&b=2"},
+			want:    false,
+		},
+		{
+			name:    "match query in any order",
+			matcher: MatchQuery,
+			request: &CassetteRequest{URL: "http://example.com/api?b=2&a=1"},
+			want:    true,
+		},
+		{
+			name:    "mismatch query",
+			matcher: MatchQuery,
+			request: &CassetteRequest{URL: "http://example.com/api?a=1"},
+			want:    false,
+		},
+		{
+			name:    "match headers",
+			matcher: MatchHeaders("x-version"),
+			request: &CassetteRequest{Headers: http.Header{"X-Version": []string{"1"}}},
+			want:    true,
+		},
+		{
+			name:    "mismatch headers",
+			matcher: MatchHeaders("X-Version"),
+			request: &CassetteRequest{Headers: http.Header{}},
+			want:    false,
+		},
+		{
+			name:    "match json body in any order",
+			matcher: MatchBody,
+			request: &CassetteRequest{Body: `{"b":2, "a":1}`},
+			want:    true,
+		},
+		{
+			name:    "mismatch body",
+			matcher: MatchBody,
+			request: &CassetteRequest{Body: `{"a":1}`},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher(tt.request, recorded); got != tt.want {
+				t.Errorf("matcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Cassette files ending in .yaml or .yml are YAML. Without a YAML dependency
+// they are written with the subset of YAML read back here: block mappings and
+// sequences, empty flow collections, and plain or quoted scalars on one line.
+
+func isYAMLCassette(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func marshalCassetteYAML(file *cassetteFile) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "version: %d\n", file.Version)
+	if len(file.Interactions) == 0 {
+		b.WriteString("interactions: []\n")
+		return b.Bytes()
+	}
+
+	b.WriteString("interactions:\n")
+	for _, interaction := range file.Interactions {
+		request, response := &interaction.Request, &interaction.Response
+		b.WriteString("  - request:\n")
+		writeYAMLField(&b, 6, "method", yamlQuote(request.Method))
+		writeYAMLField(&b, 6, "url", yamlQuote(request.URL))
+		writeYAMLHeaders(&b, 6, request.Headers)
+		writeYAMLBody(&b, 6, request.Body, request.BodyEncoding)
+		b.WriteString("    response:\n")
+		writeYAMLField(&b, 6, "status_code", strconv.Itoa(response.StatusCode))
+		writeYAMLHeaders(&b, 6, response.Headers)
+		writeYAMLBody(&b, 6, response.Body, response.BodyEncoding)
+	}
+	return b.Bytes()
+}
+
+func writeYAMLField(b *bytes.Buffer, indent int, key, value string) {
+	b.WriteString(strings.Repeat(" ", indent) + key + ": " + value + "\n")
+}
+
+func writeYAMLHeaders(b *bytes.Buffer, indent int, header map[string][]string) {
+	if len(header) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString(strings.Repeat(" ", indent) + "headers:\n")
+	for _, name := range names {
+		if len(header[name]) == 0 {
+			writeYAMLField(b, indent+2, yamlQuote(name), "[]")
+			continue
+		}
+		b.WriteString(strings.Repeat(" ", indent+2) + yamlQuote(name) + ":\n")
+		for _, value := range header[name] {
+			b.WriteString(strings.Repeat(" ", indent+4) + "- " + yamlQuote(value) + "\n")
+		}
+	}
+}
+
+func writeYAMLBody(b *bytes.Buffer, indent int, body, encoding string) {
+	if body != "" {
+		writeYAMLField(b, indent, "body", yamlQuote(body))
+	}
+	if encoding != "" {
+		writeYAMLField(b, indent, "body_encoding", yamlQuote(encoding))
+	}
+}
+
+// yamlQuote quotes s as a double quoted scalar, JSON strings are valid ones.
+func yamlQuote(s string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// unmarshalCassetteYAML parses data into values encoding/json would produce
+// and decodes them into file, so the json tags apply to both formats.
+func unmarshalCassetteYAML(data []byte, file *cassetteFile) error {
+	value, err := parseYAML(data)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, file)
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(text, " \r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed[0] == '#' || len(p.lines) == 0 && trimmed == "---" {
+			continue
+		}
+		if trimmed[0] == '\t' {
+			return nil, fmt.Errorf("yaml line %d: tabs are not allowed in indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+
+	value, err := p.parse(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) parse(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok, err := splitYAMLKey(line.text); err != nil {
+		return nil, fmt.Errorf("yaml line %d: %w", line.number, err)
+	} else if ok {
+		return p.parseMapping(indent)
+	}
+
+	p.pos++
+	value, err := parseYAMLScalar(line.text)
+	if err != nil {
+		return nil, fmt.Errorf("yaml line %d: %w", line.number, err)
+	}
+	return value, nil
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			var item interface{}
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				var err error
+				item, err = p.parse(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// the item starts on the line of its dash, as if it was indented there
+		itemIndent := indent + len(line.text) - len(rest)
+		p.lines[p.pos] = yamlLine{number: line.number, indent: itemIndent, text: rest}
+		item, err := p.parse(itemIndent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	mapping := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isYAMLSequenceItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		key, rest, ok, err := splitYAMLKey(line.text)
+		if err != nil || !ok {
+			return nil, fmt.Errorf("yaml line %d: expected a mapping key", line.number)
+		}
+		if _, duplicate := mapping[key]; duplicate {
+			return nil, fmt.Errorf("yaml line %d: duplicate key %s", line.number, key)
+		}
+		p.pos++
+
+		var value interface{}
+		switch {
+		case rest != "":
+			value, err = parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("yaml line %d: %w", line.number, err)
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			value, err = p.parse(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text):
+			// a sequence may be indented as much as its key
+			value, err = p.parseSequence(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapping[key] = value
+	}
+	return mapping, nil
+}
+
+// splitYAMLKey splits "key: value" into the key and the rest of the line.
+func splitYAMLKey(text string) (key, rest string, ok bool, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		key, rest, err = parseYAMLQuoted(text)
+		if err != nil {
+			return
+		}
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false, nil
+		}
+		return key, yamlValue(rest[1:]), true, nil
+	}
+
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false, nil
+		}
+		i = len(text) - 1
+	}
+	if strings.Contains(text[:i], " #") {
+		return "", "", false, nil
+	}
+	return text[:i], yamlValue(text[i+1:]), true, nil
+}
+
+// yamlValue trims the value after a key, a comment is no value.
+func yamlValue(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+	return text
+}
+
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch text[0] {
+	case '"', '\'':
+		value, rest, err := parseYAMLQuoted(text)
+		if err != nil {
+			return nil, err
+		}
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, fmt.Errorf("unexpected %q after quoted scalar", rest)
+		}
+		return value, nil
+	case '[', '{':
+		switch text {
+		case "[]":
+			return []interface{}{}, nil
+		case "{}":
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("unsupported flow collection %s", text)
+	case '|', '>', '&', '*', '!', '%', '@', '`':
+		return nil, fmt.Errorf("unsupported scalar %s", text)
+	}
+
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = strings.TrimSpace(text[:i])
+	}
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+// parseYAMLQuoted parses the quoted scalar text starts with and returns the
+// rest of the line after it.
+func parseYAMLQuoted(text string) (value, rest string, err error) {
+	quote := text[0]
+	var b strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == quote:
+			return b.String(), strings.TrimSpace(text[i+1:]), nil
+		case c == '\\' && quote == '"':
+			var n int
+			n, err = writeYAMLEscape(&b, text[i+1:])
+			if err != nil {
+				return
+			}
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted scalar %s", text)
+}
+
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': `"`, '/': "/", '\\': `\`,
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// writeYAMLEscape writes the character of the escape sequence s starts with
+// and returns its length.
+func writeYAMLEscape(b *strings.Builder, s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("unterminated escape sequence")
+	}
+
+	if value, ok := yamlEscapes[s[0]]; ok {
+		b.WriteString(value)
+		return 1, nil
+	}
+
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+	if size == 0 || len(s) < 1+size {
+		return 0, fmt.Errorf("invalid escape sequence \\%s", s[:1])
+	}
+	code, err := strconv.ParseUint(s[1:1+size], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape sequence \\%s", s[:1+size])
+	}
+	r := rune(code)
+	n := size
+
+	// JSON writes characters outside the BMP as UTF-16 surrogate pairs
+	if utf16.IsSurrogate(r) && len(s) >= 2+2*size && s[1+size:3+size] == `\u` {
+		if low, err := strconv.ParseUint(s[3+size:3+2*size], 16, 32); err == nil {
+			if pair := utf16.DecodeRune(r, rune(low)); pair != utf8.RuneError {
+				r, n = pair, n+2+size
+			}
+		}
+	}
+	b.WriteRune(r)
+	return 1 + n, nil
+}
@@ -0,0 +1,102 @@
+package request
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_marshalCassetteYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		file *cassetteFile
+	}{
+		{
+			name: "empty",
+			file: &cassetteFile{Version: 1, Interactions: []*CassetteInteraction{}},
+		},
+		{
+			name: "interactions",
+			file: &cassetteFile{Version: 1, Interactions: []*CassetteInteraction{
+				{
+					Request: CassetteRequest{
+						Method:  http.MethodPost,
+						URL:     "/users?q=a: b #c",
+						Headers: http.Header{"Content-Type": {"application/json"}, "X-Empty": {}, "X-Multi": {"1", "'2'"}},
+						Body:    "{\"name\": \"it's\\n\\\"quoted\\\"\"}\n\t<tag> é 😀 \u2028",
+					},
+					Response: CassetteResponse{
+						StatusCode:   http.StatusCreated,
+						Headers:      http.Header{"Location": {"/users/1"}},
+						Body:         "AAEC/w==",
+						BodyEncoding: "base64",
+					},
+				},
+				{
+					Request:  CassetteRequest{Method: http.MethodGet, URL: "/"},
+					Response: CassetteResponse{StatusCode: http.StatusNoContent},
+				},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := marshalCassetteYAML(tt.file)
+
+			var got cassetteFile
+			err := unmarshalCassetteYAML(data, &got)
+			if err != nil {
+				t.Errorf("unmarshalCassetteYAML() error = %v\n%s", err, data)
+				return
+			}
+			if !reflect.DeepEqual(&got, tt.file) {
+				t.Errorf("unmarshalCassetteYAML() = %+v, want %+v\n%s", got, tt.file, data)
+			}
+		})
+	}
+}
+
+func Test_parseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "block collections",
+			data: "---\n# comment\nversion: 1 # trailing\nitems:\n- a\n-   b: 2\n    c: true\n-\n  - ~\nnested:\n  'it''s': \"x\\ty\\u00e9\\U0001F600\"\n  empty: []\n  none:\n",
+			want: map[string]interface{}{
+				"version": int64(1),
+				"items": []interface{}{
+					"a",
+					map[string]interface{}{"b": int64(2), "c": true},
+					[]interface{}{nil},
+				},
+				"nested": map[string]interface{}{"it's": "x\tyé😀", "empty": []interface{}{}, "none": nil},
+			},
+		},
+		{name: "scalar", data: "plain text: no\n", want: map[string]interface{}{"plain text": "no"}},
+		{name: "empty", data: "\n# only a comment\n", want: nil},
+		{name: "tab indentation", data: "a:\n\tb: 1\n", wantErr: true},
+		{name: "bad indentation", data: "a:\n    b: 1\n  c: 2\n", wantErr: true},
+		{name: "duplicate key", data: "a: 1\na: 2\n", wantErr: true},
+		{name: "flow collection", data: "a: [1, 2]\n", wantErr: true},
+		{name: "block scalar", data: "a: |\n  text\n", wantErr: true},
+		{name: "anchor", data: "a: &x 1\n", wantErr: true},
+		{name: "unterminated quote", data: "a: \"text\n", wantErr: true},
+		{name: "invalid escape", data: "a: \"\\q\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseYAML() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
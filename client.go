@@ -2,8 +2,12 @@ package request
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +18,33 @@ type Client struct {
 
 	instance  *http.Client
 	transport *http.Transport
+	signer    Signer
+	redirect  *redirectPolicy
+	proxy     *proxyConfig
+	pool      *endpointPool
+	health    *healthChecker
+	resolver  *resolverLoop
+	dns       *dnsDialer
+	hedger    *hedger
+	coalescer *coalescer
+	cache     *httpCache
+	metrics   *Metrics
+	trace     *tracing
+	logger    *requestLogger
+
+	timingsHooks []TimingsHook
+	middlewares  []func(next http.RoundTripper) http.RoundTripper
+	// replaces transport to send requests, used by mocks
+	baseRoundTripper http.RoundTripper
+
+	// kept to export requests as curl commands
+	certificateFiles []certificateFile
+	unixSocket       string
+}
+
+type certificateFile struct {
+	certFile string
+	keyFile  string
 }
 
 type ClientOption func(*Client) error
@@ -40,11 +71,55 @@ func NewClient(host string, options ...ClientOption) (client *Client, err error)
 		}
 	}
 
-	client.instance.Transport = client.transport
+	if client.health != nil && client.pool == nil {
+		return nil, errors.New("health check requires endpoints")
+	}
+
+	client.instance.Transport = client.roundTripper()
+
+	// starting goroutines comes last, so that no error path leaks them
+	if client.resolver != nil {
+		err = client.resolver.start(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if client.health != nil {
+		client.health.start(client)
+	}
 
 	return
 }
 
+func (c *Client) roundTripper() http.RoundTripper {
+	if len(c.middlewares) == 0 && c.trace == nil && c.baseRoundTripper == nil && c.pool == nil {
+		return c.transport
+	}
+
+	var roundTripper http.RoundTripper = c.transport
+	if c.baseRoundTripper != nil {
+		roundTripper = c.baseRoundTripper
+	} else if c.pool != nil {
+		transport := newEndpointTransport(c.transport, net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port))))
+		c.pool.removed = transport.remove
+		roundTripper = transport
+	}
+	for _, middleware := range c.middlewares {
+		roundTripper = middleware(roundTripper)
+	}
+	if c.trace != nil {
+		roundTripper = &tracingTransport{tracing: c.trace, next: roundTripper}
+	}
+	return roundTripper
+}
+
+func closeIdleConnections(roundTripper http.RoundTripper) {
+	if closer, ok := roundTripper.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
 func WithScheme(scheme string) ClientOption {
 	return func(c *Client) error {
 		c.Scheme = scheme
@@ -102,6 +177,7 @@ func WithClientCertificateFile(clientCrtFile, clientKeyFile string) ClientOption
 		if err != nil {
 			return err
 		}
+		c.certificateFiles = append(c.certificateFiles, certificateFile{certFile: clientCrtFile, keyFile: clientKeyFile})
 
 		c.transport.TLSClientConfig.Certificates = append(
 			c.transport.TLSClientConfig.Certificates, certificate,
@@ -135,6 +211,26 @@ func WithSkipVerifyCertificates() ClientOption {
 	}
 }
 
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *Client) (err error) {
+		if jar == nil {
+			jar, err = NewCookieJar()
+			if err != nil {
+				return
+			}
+		}
+		c.instance.Jar = jar
+		return
+	}
+}
+
+func WithSigner(signer Signer) ClientOption {
+	return func(c *Client) error {
+		c.signer = signer
+		return nil
+	}
+}
+
 func (c *Client) BaseURL() string {
 	return fmt.Sprintf("%s://%s:%d", c.Scheme, c.Host, c.Port)
 }
@@ -145,6 +241,88 @@ func (c *Client) Do(req *Request) (*Response, error) {
 		return nil, err
 	}
 
+	if c.signer != nil {
+		err = c.signer.Sign(httpRequest)
+		if err != nil {
+			return nil, fmt.Errorf("sign request error %w", err)
+		}
+	}
+
+	if c.logger != nil {
+		httpRequest = httpRequest.WithContext(withAttemptCounter(httpRequest.Context()))
+	}
+
+	send := func(httpRequest *http.Request) (*Response, error) {
+		return c.roundTrip(req, httpRequest)
+	}
+
+	if c.hedger != nil && c.hedger.hedgeable(httpRequest) {
+		roundTrip := send
+		send = func(httpRequest *http.Request) (*Response, error) {
+			return c.hedger.do(httpRequest, roundTrip)
+		}
+	}
+
+	if c.coalescer != nil {
+		dispatch := send
+		send = func(httpRequest *http.Request) (*Response, error) {
+			if !c.coalescer.coalescible(httpRequest) {
+				return dispatch(httpRequest)
+			}
+			return c.coalescer.do(httpRequest, dispatch)
+		}
+	}
+
+	if c.cache != nil {
+		fetch := send
+		send = func(httpRequest *http.Request) (*Response, error) {
+			return c.cache.do(httpRequest, fetch)
+		}
+	}
+
+	if c.metrics != nil {
+		measure := send
+		send = func(httpRequest *http.Request) (*Response, error) {
+			return c.metrics.do(req, httpRequest, measure)
+		}
+	}
+
+	if c.trace != nil {
+		return c.trace.do(httpRequest, send)
+	}
+
+	return send(httpRequest)
+}
+
+func (c *Client) roundTrip(req *Request, httpRequest *http.Request) (*Response, error) {
+	if c.pool != nil {
+		return c.doWithEndpoints(req, httpRequest)
+	}
+
+	return c.send(httpRequest)
+}
+
+func (c *Client) send(httpRequest *http.Request) (*Response, error) {
+	if c.logger != nil {
+		return c.logger.do(httpRequest, c.sendTimed)
+	}
+
+	return c.sendTimed(httpRequest)
+}
+
+func (c *Client) sendTimed(httpRequest *http.Request) (resp *Response, err error) {
+	tracer := newTimingsTracer()
+	httpRequest = httpRequest.WithContext(httptrace.WithClientTrace(httpRequest.Context(), tracer.clientTrace()))
+	defer func() {
+		timings := tracer.finish()
+		if resp != nil {
+			resp.Timings = timings
+		}
+		for _, hook := range c.timingsHooks {
+			hook(httpRequest, timings, err)
+		}
+	}()
+
 	httpResponse, err := c.instance.Do(httpRequest)
 	if err != nil {
 		return nil, err
@@ -152,3 +330,15 @@ func (c *Client) Do(req *Request) (*Response, error) {
 
 	return parseResponse(httpResponse)
 }
+
+// Close stops background work of the client and closes idle connections.
+func (c *Client) Close() error {
+	if c.health != nil {
+		c.health.stop()
+	}
+	if c.resolver != nil {
+		c.resolver.stop()
+	}
+	c.instance.CloseIdleConnections()
+	return nil
+}
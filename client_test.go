@@ -2,7 +2,10 @@ package request
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -240,3 +243,19 @@ func TestWithSkipVerifyCertificates(t *testing.T) {
 		})
 	}
 }
+
+func newTestClient(server *httptest.Server, options ...ClientOption) (*Client, error) {
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(serverURL.Port(), 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(
+		serverURL.Hostname(),
+		append([]ClientOption{WithScheme(serverURL.Scheme), WithPort(uint16(port))}, options...)...,
+	)
+}
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type coalescer struct {
+	headers []string
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done    chan struct{}
+	resp    *Response
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// coalescedCredentialHeaders are always part of the key, so that callers
+// never share a response made with other credentials.
+var coalescedCredentialHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// WithCoalescing shares one response among concurrent identical GET and HEAD
+// requests, they are identical when method, URL, Host, the credential headers
+// and the given headers are the same. Every caller gets its own copy of the
+// response.
+func WithCoalescing(headers ...string) ClientOption {
+	return func(c *Client) error {
+		canonicalHeaders := append([]string{}, coalescedCredentialHeaders...)
+		for _, header := range headers {
+			header = http.CanonicalHeaderKey(header)
+			if !containsString(canonicalHeaders, header) {
+				canonicalHeaders = append(canonicalHeaders, header)
+			}
+		}
+		c.coalescer = &coalescer{
+			headers: canonicalHeaders,
+			calls:   map[string]*coalescedCall{},
+		}
+		return nil
+	}
+}
+
+func (c *coalescer) coalescible(httpRequest *http.Request) bool {
+	return (httpRequest.Method == http.MethodGet || httpRequest.Method == http.MethodHead) &&
+		(httpRequest.Body == nil || httpRequest.Body == http.NoBody)
+}
+
+func (c *coalescer) key(httpRequest *http.Request) string {
+	var builder strings.Builder
+	builder.WriteString(httpRequest.Method)
+	builder.WriteString(" ")
+	builder.WriteString(httpRequest.URL.String())
+	builder.WriteString(" ")
+	builder.WriteString(httpRequest.Host)
+	for _, header := range c.headers {
+		builder.WriteString("\n")
+		builder.WriteString(header)
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(httpRequest.Header.Values(header), ", "))
+	}
+	return builder.String()
+}
+
+// do sends the request unless an identical one is in flight. The shared
+// request is only canceled when every caller waiting for it is gone.
+func (c *coalescer) do(httpRequest *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	key := c.key(httpRequest)
+
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.WithoutCancel(httpRequest.Context()))
+		call = &coalescedCall{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		c.calls[key] = call
+
+		go func() {
+			defer cancel()
+			resp, err := send(httpRequest.WithContext(ctx))
+
+			c.mu.Lock()
+			c.forget(key, call)
+			call.resp, call.err = resp, err
+			c.mu.Unlock()
+
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.resp.clone(), call.err
+	case <-httpRequest.Context().Done():
+		c.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			c.forget(key, call)
+			call.cancel()
+		}
+		c.mu.Unlock()
+		return nil, httpRequest.Context().Err()
+	}
+}
+
+func (c *coalescer) forget(key string, call *coalescedCall) {
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
@@ -0,0 +1,195 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func newBlockingServer() (*httptest.Server, *int32, chan struct{}) {
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("X-Request", "done")
+		_, _ = w.Write([]byte("shared"))
+	}))
+	return server, &requests, release
+}
+
+func coalescedWaiters(client *Client) int {
+	client.coalescer.mu.Lock()
+	defer client.coalescer.mu.Unlock()
+
+	waiters := 0
+	for _, call := range client.coalescer.calls {
+		waiters += call.waiters
+	}
+	return waiters
+}
+
+func TestWithCoalescing(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		headers      []string
+		header       string
+		wantRequests int32
+	}{
+		{
+			name:         "coalesce get",
+			method:       http.MethodGet,
+			wantRequests: 1,
+		},
+		{
+			name:         "do not coalesce different selected header",
+			method:       http.MethodGet,
+			headers:      []string{"x-tenant"},
+			wantRequests: 2,
+		},
+		{
+			name:         "do not coalesce different credentials",
+			method:       http.MethodGet,
+			header:       "Authorization",
+			wantRequests: 2,
+		},
+		{
+			name:         "do not coalesce different cookies",
+			method:       http.MethodGet,
+			header:       "Cookie",
+			wantRequests: 2,
+		},
+		{
+			name:         "do not coalesce post",
+			method:       http.MethodPost,
+			wantRequests: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests, release := newBlockingServer()
+			defer server.Close()
+
+			header := tt.header
+			if header == "" {
+				header = "X-Tenant"
+			}
+			client, err := newTestClient(server, WithCoalescing(tt.headers...))
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+
+			var wg sync.WaitGroup
+			responses := make([]*Response, 4)
+			errs := make([]error, 4)
+			for i := range responses {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					req, _ := NewRequest(
+						tt.method, "/api/test",
+						WithHeaders(map[string]string{header: []string{"a", "b"}[i%2]}),
+					)
+					responses[i], errs[i] = client.Do(req)
+				}(i)
+			}
+
+			waitFor(t, func() bool {
+				return atomic.LoadInt32(requests) == tt.wantRequests &&
+					(tt.method != http.MethodGet || coalescedWaiters(client) == len(responses))
+			})
+			close(release)
+			wg.Wait()
+
+			if got := atomic.LoadInt32(requests); got != tt.wantRequests {
+				t.Errorf("Do() requests = %v, want %v", got, tt.wantRequests)
+			}
+			for i, resp := range responses {
+				if errs[i] != nil {
+					t.Errorf("Do() error = %v", errs[i])
+					return
+				}
+				if string(resp.RawBody) != "shared" || resp.Header.Get("X-Request") != "done" {
+					t.Errorf("Do() response = %v %s, want shared", resp.Header, resp.RawBody)
+				}
+			}
+
+			responses[0].RawBody[0] = 'S'
+			responses[0].Header.Set("X-Request", "changed")
+			if string(responses[2].RawBody) != "shared" || responses[2].Header.Get("X-Request") != "done" {
+				t.Errorf("Do() responses share RawBody or Header")
+			}
+		})
+	}
+}
+
+func TestWithCoalescing_context(t *testing.T) {
+	server, requests, release := newBlockingServer()
+	defer server.Close()
+
+	client, _ := newTestClient(server, WithCoalescing())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		req, _ := NewRequest(http.MethodGet, "/api/test", WithContext(ctx))
+		_, err := client.Do(req)
+		canceled <- err
+	}()
+
+	waited := make(chan *Response, 1)
+	go func() {
+		req, _ := NewRequest(http.MethodGet, "/api/test")
+		resp, _ := client.Do(req)
+		waited <- resp
+	}()
+
+	waitFor(t, func() bool { return coalescedWaiters(client) == 2 })
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	if resp := <-waited; resp == nil || string(resp.RawBody) != "shared" {
+		t.Errorf("Do() response = %v, want shared", resp)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("Do() requests = %v, want %v", got, 1)
+	}
+}
+
+func TestWithCoalescing_cancelAll(t *testing.T) {
+	server, requests, release := newBlockingServer()
+	defer server.Close()
+	defer close(release)
+
+	client, _ := newTestClient(server, WithCoalescing())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		req, _ := NewRequest(http.MethodGet, "/api/test", WithContext(ctx))
+		_, err := client.Do(req)
+		done <- err
+	}()
+
+	waitFor(t, func() bool { return atomic.LoadInt32(requests) == 1 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want %v", err, context.Canceled)
+	}
+	if got := coalescedWaiters(client); got != 0 {
+		t.Errorf("Do() waiters = %v, want %v", got, 0)
+	}
+}
@@ -0,0 +1,141 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ifMatchHeader           = "If-Match"
+	ifUnmodifiedSinceHeader = "If-Unmodified-Since"
+
+	defaultReadModifyWriteAttempts = 3
+)
+
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// quoteETag quotes an entity tag unless it already is, so both the value of
+// the ETag header and a bare tag can be passed.
+func quoteETag(etag string) string {
+	if etag == "*" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+func joinETags(etags []string) (string, error) {
+	if len(etags) == 0 {
+		return "", errors.New("no entity tag")
+	}
+	quoted := make([]string, 0, len(etags))
+	for _, etag := range etags {
+		quoted = append(quoted, quoteETag(etag))
+	}
+	return strings.Join(quoted, ", "), nil
+}
+
+func WithIfMatch(etags ...string) RequestOption {
+	return func(r *Request) error {
+		value, err := joinETags(etags)
+		if err != nil {
+			return err
+		}
+		r.Headers.Set(ifMatchHeader, value)
+		return nil
+	}
+}
+
+func WithIfNoneMatch(etags ...string) RequestOption {
+	return func(r *Request) error {
+		value, err := joinETags(etags)
+		if err != nil {
+			return err
+		}
+		r.Headers.Set(ifNoneMatchHeader, value)
+		return nil
+	}
+}
+
+func WithIfModifiedSince(t time.Time) RequestOption {
+	return func(r *Request) error {
+		r.Headers.Set(ifModifiedSinceHeader, t.UTC().Format(http.TimeFormat))
+		return nil
+	}
+}
+
+func WithIfUnmodifiedSince(t time.Time) RequestOption {
+	return func(r *Request) error {
+		r.Headers.Set(ifUnmodifiedSinceHeader, t.UTC().Format(http.TimeFormat))
+		return nil
+	}
+}
+
+// MutateFunc builds the write request from the current state of a resource.
+type MutateFunc func(current *Response) (*Request, error)
+
+// ReadModifyWrite reads a resource, lets mutate build the write request and
+// sends it only if the resource is unchanged, using the strong ETag or else
+// the Last-Modified of the read response. The loop starts again on 412
+// Precondition Failed, at most maxAttempts times, ErrPreconditionFailed is
+// returned with the last response when all attempts failed.
+func (c *Client) ReadModifyWrite(read *Request, mutate MutateFunc, maxAttempts int) (*Response, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultReadModifyWriteAttempts
+	}
+
+	// the state to modify must not come from the cache without revalidation
+	fresh := *read
+	fresh.Headers = read.Headers.Clone()
+	if fresh.Headers == nil {
+		fresh.Headers = http.Header{}
+	}
+	fresh.Headers.Set(cacheControlHeader, "no-cache")
+
+	var resp *Response
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		current, err := c.Do(&fresh)
+		if err != nil {
+			return nil, err
+		}
+		if current.StatusCode < 200 || current.StatusCode >= 300 {
+			return current, fmt.Errorf("read resource status %d", current.StatusCode)
+		}
+
+		write, err := mutate(current)
+		if err != nil {
+			return nil, err
+		}
+		if write == nil {
+			return nil, errors.New("mutate returned nil request")
+		}
+		if write.Headers == nil {
+			write.Headers = http.Header{}
+		}
+
+		// If-Match uses the strong comparison, a weak ETag never matches
+		etag := current.ETag()
+		weak := strings.HasPrefix(etag, "W/")
+		if etag != "" && !weak {
+			write.Headers.Set(ifMatchHeader, etag)
+		} else if lastModified := current.Header.Get(lastModifiedHeader); lastModified != "" {
+			write.Headers.Set(ifUnmodifiedSinceHeader, lastModified)
+		} else if weak {
+			return nil, fmt.Errorf("read response has a weak ETag %s and no Last-Modified", etag)
+		} else {
+			return nil, errors.New("read response has no ETag or Last-Modified")
+		}
+
+		resp, err = c.Do(write)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			return resp, nil
+		}
+	}
+
+	return resp, ErrPreconditionFailed
+}
@@ -0,0 +1,195 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConditionalOptions(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+8", 8*3600))
+	tests := []struct {
+		name    string
+		option  RequestOption
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "if-match bare tag", option: WithIfMatch("v1"), header: "If-Match", want: `"v1"`},
+		{name: "if-match quoted tags", option: WithIfMatch(`"v1"`, `W/"v2"`), header: "If-Match", want: `"v1", W/"v2"`},
+		{name: "if-match without tag", option: WithIfMatch(), wantErr: true},
+		{name: "if-none-match any", option: WithIfNoneMatch("*"), header: "If-None-Match", want: "*"},
+		{
+			name:   "if-modified-since",
+			option: WithIfModifiedSince(modified),
+			header: "If-Modified-Since",
+			want:   "Mon, 01 Jan 2024 19:04:05 GMT",
+		},
+		{
+			name:   "if-unmodified-since",
+			option: WithIfUnmodifiedSince(modified),
+			header: "If-Unmodified-Since",
+			want:   "Mon, 01 Jan 2024 19:04:05 GMT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewRequest(http.MethodPut, "/api/test", tt.option)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got := req.Headers.Get(tt.header); got != tt.want {
+				t.Errorf("NewRequest() %s = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+type versionedResource struct {
+	mu        sync.Mutex
+	version   int
+	value     string
+	conflicts int
+	writes    int
+}
+
+func (v *versionedResource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	etag := `"` + strconv.Itoa(v.version) + `"`
+	switch r.Method {
+	case http.MethodGet:
+		if v.conflicts > 0 {
+			// another writer changes the resource after it is read
+			v.conflicts--
+			defer func() { v.version++ }()
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(v.value))
+	case http.MethodPut:
+		v.writes++
+		if r.Header.Get("If-Match") != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		v.version++
+		v.value = r.URL.Query().Get("value")
+		w.Header().Set("ETag", `"`+strconv.Itoa(v.version)+`"`)
+	}
+}
+
+func TestClient_ReadModifyWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		conflicts   int
+		maxAttempts int
+		wantWrites  int
+		wantValue   string
+		wantErr     error
+	}{
+		{name: "no conflict", conflicts: 0, maxAttempts: 3, wantWrites: 1, wantValue: "a!"},
+		{name: "retry on conflict", conflicts: 2, maxAttempts: 3, wantWrites: 3, wantValue: "a!"},
+		{name: "too many conflicts", conflicts: 3, maxAttempts: 3, wantWrites: 3, wantValue: "a", wantErr: ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &versionedResource{value: "a", conflicts: tt.conflicts}
+			server := httptest.NewServer(resource)
+			defer server.Close()
+
+			client, _ := newTestClient(server)
+			read, _ := NewRequest(http.MethodGet, "/api/resource")
+			resp, err := client.ReadModifyWrite(read, func(current *Response) (*Request, error) {
+				return NewRequest(
+					http.MethodPut, "/api/resource",
+					WithQueryParams(NewQueryParams(map[string]string{"value": string(current.RawBody) + "!"})),
+				)
+			}, tt.maxAttempts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadModifyWrite() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if resp == nil {
+				t.Errorf("ReadModifyWrite() response = nil")
+				return
+			}
+			if resource.writes != tt.wantWrites || resource.value != tt.wantValue {
+				t.Errorf("ReadModifyWrite() writes = %v value = %v, want %v %v",
+					resource.writes, resource.value, tt.wantWrites, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestClient_ReadModifyWrite_noValidator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client, _ := newTestClient(server)
+	read, _ := NewRequest(http.MethodGet, "/api/resource")
+	_, err := client.ReadModifyWrite(read, func(current *Response) (*Request, error) {
+		return NewRequest(http.MethodPut, "/api/resource")
+	}, 1)
+	if err == nil {
+		t.Errorf("ReadModifyWrite() error = %v, wantErr true", err)
+	}
+}
+
+func TestClient_ReadModifyWrite_weakETag(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastModified string
+		wantErr      bool
+		wantHeader   string
+	}{
+		{name: "last modified", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", wantHeader: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{name: "no last modified", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes int
+			var ifMatch, ifUnmodifiedSince string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					writes++
+					ifMatch, ifUnmodifiedSince = r.Header.Get("If-Match"), r.Header.Get("If-Unmodified-Since")
+					return
+				}
+				w.Header().Set("ETag", `W/"1"`)
+				if tt.lastModified != "" {
+					w.Header().Set("Last-Modified", tt.lastModified)
+				}
+			}))
+			defer server.Close()
+
+			client, _ := newTestClient(server)
+			read, _ := NewRequest(http.MethodGet, "/api/resource")
+			_, err := client.ReadModifyWrite(read, func(current *Response) (*Request, error) {
+				return NewRequest(http.MethodPut, "/api/resource")
+			}, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadModifyWrite() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if writes != 0 {
+					t.Errorf("ReadModifyWrite() writes = %v, want 0", writes)
+				}
+				return
+			}
+			if ifMatch != "" || ifUnmodifiedSince != tt.wantHeader {
+				t.Errorf("ReadModifyWrite() If-Match = %q If-Unmodified-Since = %q, want %q",
+					ifMatch, ifUnmodifiedSince, tt.wantHeader)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package request
 
 const (
+	authorizationHeader = "Authorization"
+
 	contentTypeHeader       = "Content-Type"
 	contentTypeJson         = "application/json"
 	contentTypeJsonWithUTF8 = contentTypeJson + "; charset=UTF-8"
@@ -0,0 +1,204 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CookieJar is a net/http/cookiejar based jar which remembers the cookies it
+// holds, so they can be saved to and loaded from a JSON file.
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]*persistedCookie
+
+	now func() time.Time
+}
+
+type persistedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Path     string        `json:"path,omitempty"`
+	Domain   string        `json:"domain,omitempty"`
+	Expires  time.Time     `json:"expires,omitempty"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+func NewCookieJar() (*CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CookieJar{
+		jar:     jar,
+		cookies: map[string]*persistedCookie{},
+		now:     time.Now,
+	}, nil
+}
+
+// LoadCookieJar creates a jar from a file written by Save. A missing file
+// results in an empty jar.
+func LoadCookieJar(filename string) (*CookieJar, error) {
+	jar, err := NewCookieJar()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cookie file error %w", err)
+	}
+
+	var cookies []*persistedCookie
+	err = json.Unmarshal(data, &cookies)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal cookie file error %w", err)
+	}
+
+	for _, cookie := range cookies {
+		u, err := url.Parse(cookie.URL)
+		if err != nil {
+			return nil, fmt.Errorf("parse cookie url error %w", err)
+		}
+		jar.SetCookies(u, []*http.Cookie{cookie.httpCookie()})
+	}
+
+	return jar, nil
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	for _, cookie := range cookies {
+		key := cookieKey(u, cookie)
+
+		expires := cookie.Expires
+		if cookie.MaxAge > 0 {
+			expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		if cookie.MaxAge < 0 || (!expires.IsZero() && !expires.After(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+
+		j.cookies[key] = &persistedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			Expires:  expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: cookie.SameSite,
+		}
+	}
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+func (j *CookieJar) Save(filename string) error {
+	j.mu.Lock()
+	now := j.now()
+	cookies := make([]*persistedCookie, 0, len(j.cookies))
+	for key, cookie := range j.cookies {
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		cookies = append(cookies, cookie)
+	}
+	j.mu.Unlock()
+
+	sort.Slice(cookies, func(i, k int) bool {
+		if cookies[i].URL != cookies[k].URL {
+			return cookies[i].URL < cookies[k].URL
+		}
+		return cookies[i].Name < cookies[k].Name
+	})
+
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cookies error %w", err)
+	}
+
+	err = writeFileAtomic(filename, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("write cookie file error %w", err)
+	}
+	return nil
+}
+
+func (c *persistedCookie) httpCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: c.SameSite,
+	}
+}
+
+func cookieKey(u *url.URL, cookie *http.Cookie) string {
+	domain := cookie.Domain
+	if domain == "" {
+		domain = u.Hostname()
+	}
+
+	cookiePath := cookie.Path
+	if cookiePath == "" {
+		cookiePath = path.Dir(u.Path)
+	}
+
+	return domain + ";" + cookiePath + ";" + cookie.Name
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames
+// it, so readers never see a partially written file.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Chmod(perm)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filename)
+}
@@ -0,0 +1,167 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWithCookieJar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "token", Path: "/"})
+		case "/me":
+			cookie, err := r.Cookie("session")
+			if err != nil || cookie.Value != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		options        []ClientOption
+		wantStatusCode int
+	}{
+		{
+			name:           "request without cookie jar",
+			options:        nil,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "request with default cookie jar",
+			options:        []ClientOption{WithCookieJar(nil)},
+			wantStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(server, tt.options...)
+
+			login, _ := NewRequest(http.MethodPost, "/login")
+			resp, err := client.Do(login)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if len(resp.Cookies()) != 1 {
+				t.Errorf("Cookies() = %v, want 1 cookie", resp.Cookies())
+			}
+
+			me, _ := NewRequest(http.MethodGet, "/me")
+			resp, err = client.Do(me)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("Do() StatusCode = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestCookieJar_Save(t *testing.T) {
+	now := time.Now()
+	u, _ := url.Parse("https://example.com/api/login")
+	tests := []struct {
+		name        string
+		cookies     []*http.Cookie
+		wantCookies []*http.Cookie
+	}{
+		{
+			name: "save session and persistent cookies",
+			cookies: []*http.Cookie{
+				{Name: "session", Value: "token", Path: "/"},
+				{Name: "remember", Value: "me", Path: "/", MaxAge: 3600},
+			},
+			wantCookies: []*http.Cookie{
+				{Name: "remember", Value: "me"},
+				{Name: "session", Value: "token"},
+			},
+		},
+		{
+			name: "save without expired and deleted cookies",
+			cookies: []*http.Cookie{
+				{Name: "session", Value: "token", Path: "/"},
+				{Name: "session", Value: "", Path: "/", MaxAge: -1},
+				{Name: "old", Value: "value", Path: "/", Expires: now.Add(-time.Hour)},
+				{Name: "new", Value: "value", Path: "/", Expires: now.Add(time.Hour)},
+			},
+			wantCookies: []*http.Cookie{
+				{Name: "new", Value: "value"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "cookies.json")
+
+			jar, _ := NewCookieJar()
+			jar.SetCookies(u, tt.cookies)
+			err := jar.Save(filename)
+			if err != nil {
+				t.Errorf("Save() error = %v", err)
+				return
+			}
+
+			loaded, err := LoadCookieJar(filename)
+			if err != nil {
+				t.Errorf("LoadCookieJar() error = %v", err)
+				return
+			}
+			got := loaded.Cookies(u)
+			sort.Slice(got, func(i, k int) bool { return got[i].Name < got[k].Name })
+			if !reflect.DeepEqual(got, tt.wantCookies) {
+				t.Errorf("LoadCookieJar() cookies = %v, want %v", got, tt.wantCookies)
+			}
+		})
+	}
+}
+
+func TestLoadCookieJar(t *testing.T) {
+	jar, err := LoadCookieJar(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Errorf("LoadCookieJar() error = %v", err)
+		return
+	}
+	u, _ := url.Parse("https://example.com/")
+	if got := jar.Cookies(u); len(got) != 0 {
+		t.Errorf("LoadCookieJar() cookies = %v, want empty", got)
+	}
+}
+
+func Test_writeFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "data.json")
+	_ = os.WriteFile(filename, []byte("old"), 0o644)
+
+	err := writeFileAtomic(filename, []byte("new"), 0o600)
+	if err != nil {
+		t.Errorf("writeFileAtomic() error = %v", err)
+		return
+	}
+	data, _ := os.ReadFile(filename)
+	if string(data) != "new" {
+		t.Errorf("writeFileAtomic() data = %s, want new", data)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0o600 {
+		t.Errorf("writeFileAtomic() perm = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("writeFileAtomic() left %d files, want 1", len(entries))
+	}
+
+	err = writeFileAtomic(filepath.Join(dir, "missing", "data.json"), []byte("new"), 0o600)
+	if err == nil {
+		t.Errorf("writeFileAtomic() error = %v, wantErr true", err)
+	}
+}
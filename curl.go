@@ -0,0 +1,220 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToCurl renders the request as sent by client as a curl command. Form files
+// are referenced by their file name, and client certificates are only added
+// when loaded with WithClientCertificateFile. The request is signed when the
+// client has a signer, unless its body is a FormBodyParams whose multipart
+// boundary curl chooses itself.
+func (req *Request) ToCurl(client *Client) (string, error) {
+	withoutBody := *req
+	withoutBody.BodyParams = nil
+	httpRequest, err := withoutBody.build(client.BaseURL())
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"curl"}
+
+	form, isForm := req.BodyParams.(*FormBodyParams)
+	var body []byte
+	if req.BodyParams != nil && !isForm {
+		var contentType string
+		contentType, body, err = buildBody(req.BodyParams)
+		if err != nil {
+			return "", err
+		}
+		// as build does
+		if contentType != "" && httpRequest.Header.Get(contentTypeHeader) != "" {
+			httpRequest.Header.Set(contentTypeHeader, contentType)
+		}
+		httpRequest.Body = io.NopCloser(bytes.NewReader(body))
+		httpRequest.ContentLength = int64(len(body))
+	}
+
+	if client.signer != nil && !isForm {
+		err = client.signer.Sign(httpRequest)
+		if err != nil {
+			return "", fmt.Errorf("sign request error %w", err)
+		}
+	}
+
+	hasBody := len(body) != 0 || isForm
+	switch {
+	case httpRequest.Method == http.MethodHead:
+		args = append(args, "--head")
+	case httpRequest.Method == http.MethodGet && !hasBody, httpRequest.Method == http.MethodPost && hasBody:
+	default:
+		args = append(args, "-X", httpRequest.Method)
+	}
+
+	args = append(args, client.curlFlags()...)
+
+	if req.Host != "" {
+		args = append(args, "-H", "Host: "+httpRequest.Host)
+	}
+	names := make([]string, 0, len(httpRequest.Header))
+	for name := range httpRequest.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range httpRequest.Header[name] {
+			args = append(args, "-H", name+": "+value)
+		}
+	}
+
+	switch {
+	case isForm:
+		args = append(args, form.curlArgs()...)
+	case len(body) != 0:
+		if httpRequest.Header.Get(contentTypeHeader) == "" {
+			// curl would send application/x-www-form-urlencoded
+			args = append(args, "-H", contentTypeHeader+":")
+		}
+		// --data-binary would read a body starting with @ from a file
+		args = append(args, "--data-raw", string(body))
+	}
+
+	args = append(args, httpRequest.URL.String())
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+func buildBody(bodyParams BodyParams) (string, []byte, error) {
+	contentType, reader, err := bodyParams.Build()
+	if err != nil {
+		return "", nil, fmt.Errorf("build body params error %w", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("read body params error %w", err)
+	}
+	return contentType, body, nil
+}
+
+func (p *FormBodyParams) curlArgs() []string {
+	keys := make([]string, 0, len(p.params))
+	for key := range p.params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		args = append(args, "--form-string", key+"="+p.params[key])
+	}
+	for _, file := range p.files {
+		args = append(args, "-F", file.FieldName+"=@"+file.FileName)
+	}
+	return args
+}
+
+func (c *Client) curlFlags() []string {
+	var args []string
+
+	if c.redirect == nil || !c.redirect.disabled {
+		args = append(args, "-L")
+		maxRedirects := defaultMaxRedirects
+		if c.redirect != nil {
+			maxRedirects = c.redirect.maxRedirects
+		}
+		args = append(args, "--max-redirs", strconv.Itoa(maxRedirects))
+	}
+
+	if c.instance.Timeout > 0 {
+		args = append(args, "--max-time", strconv.FormatFloat(c.instance.Timeout.Seconds(), 'f', -1, 64))
+	}
+
+	if !c.transport.DisableCompression {
+		args = append(args, "--compressed")
+	}
+
+	if tlsConfig := c.transport.TLSClientConfig; tlsConfig != nil && tlsConfig.InsecureSkipVerify {
+		args = append(args, "--insecure")
+	}
+	for _, file := range c.certificateFiles {
+		args = append(args, "--cert", file.certFile, "--key", file.keyFile)
+	}
+
+	if c.proxy != nil && c.proxy.url != nil {
+		args = append(args, "--proxy", c.proxy.url.String())
+		if len(c.proxy.bypass) != 0 {
+			args = append(args, "--noproxy", strings.Join(c.proxy.bypass, ","))
+		}
+	}
+
+	if c.unixSocket != "" {
+		args = append(args, "--unix-socket", c.unixSocket)
+	}
+
+	if c.dns != nil {
+		hostPorts := make([]string, 0, len(c.dns.overrides))
+		for hostPort := range c.dns.overrides {
+			hostPorts = append(hostPorts, hostPort)
+		}
+		sort.Strings(hostPorts)
+		for _, hostPort := range hostPorts {
+			args = append(args, resolveFlag(hostPort, c.dns.overrides[hostPort])...)
+		}
+	}
+
+	return args
+}
+
+// resolveFlag uses --connect-to when the port changes, which --resolve can
+// not express.
+func resolveFlag(hostPort, addr string) []string {
+	host, port, _ := net.SplitHostPort(hostPort)
+	addrHost, addrPort, _ := net.SplitHostPort(addr)
+	if strings.Contains(addrHost, ":") {
+		addrHost = "[" + addrHost + "]"
+	}
+	if port == addrPort {
+		return []string{"--resolve", host + ":" + port + ":" + addrHost}
+	}
+	return []string{"--connect-to", host + ":" + port + ":" + addrHost + ":" + addrPort}
+}
+
+// shellQuote quotes s for POSIX shells, with ANSI-C quoting for what single
+// quotes can not hold.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return s
+	}
+
+	if utf8.ValidString(s) && !strings.ContainsRune(s, 0) {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == '\'' || b == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(b)
+		case b < 0x20 || b >= 0x7f:
+			fmt.Fprintf(&builder, `\x%02x`, b)
+		default:
+			builder.WriteByte(b)
+		}
+	}
+	builder.WriteString("'")
+	return builder.String()
+}
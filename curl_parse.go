@@ -0,0 +1,619 @@
+package request
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedCurlOption = errors.New("unsupported curl option")
+
+const formURLEncodedContentType = "application/x-www-form-urlencoded"
+
+type curlCommand struct {
+	method  string
+	rawURL  string
+	host    string
+	headers http.Header
+	removed map[string]bool
+
+	data     []string
+	form     *FormBodyParams
+	get      bool
+	head     bool
+	location bool
+
+	certFile string
+	keyFile  string
+
+	clientOptions []ClientOption
+}
+
+type curlOption struct {
+	hasValue bool
+	apply    func(cmd *curlCommand, value string) error
+}
+
+// ParseCurl turns a curl command into a client and a request doing the same.
+// Like curl, redirects are only followed with -L. Data and form values read
+// from files with @, other than -F files, are not supported.
+func ParseCurl(command string) (*Client, *Request, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) == 0 || filepath.Base(args[0]) != "curl" {
+		return nil, nil, errors.New("not a curl command")
+	}
+
+	cmd := &curlCommand{
+		headers: http.Header{},
+		removed: map[string]bool{},
+	}
+	err = cmd.parse(args[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd.build()
+}
+
+func curlOptions() map[string]curlOption {
+	data := curlOption{hasValue: true, apply: func(cmd *curlCommand, value string) error {
+		if strings.HasPrefix(value, "@") {
+			return fmt.Errorf("%w: reading data from file %s", ErrUnsupportedCurlOption, value)
+		}
+		cmd.data = append(cmd.data, value)
+		return nil
+	}}
+	ignore := curlOption{apply: func(*curlCommand, string) error { return nil }}
+
+	options := map[string]curlOption{
+		"--request": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.method = value
+			return nil
+		}},
+		"--header":      {hasValue: true, apply: (*curlCommand).addHeader},
+		"--data":        data,
+		"--data-ascii":  data,
+		"--data-binary": data,
+		"--data-raw": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.data = append(cmd.data, value)
+			return nil
+		}},
+		"--data-urlencode": {hasValue: true, apply: (*curlCommand).addURLEncodedData},
+		"--form":           {hasValue: true, apply: (*curlCommand).addForm},
+		"--form-string": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			name, content, ok := strings.Cut(value, "=")
+			if !ok {
+				return fmt.Errorf("invalid curl form %s", value)
+			}
+			cmd.formParams().params[name] = content
+			return nil
+		}},
+		"--user": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			if !strings.Contains(value, ":") {
+				return fmt.Errorf("%w: --user without password", ErrUnsupportedCurlOption)
+			}
+			cmd.headers.Set(authorizationHeader, "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+			return nil
+		}},
+		"--insecure": {apply: func(cmd *curlCommand, _ string) error {
+			cmd.clientOptions = append(cmd.clientOptions, WithSkipVerifyCertificates())
+			return nil
+		}},
+		"--cert": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.certFile = value
+			return nil
+		}},
+		"--key": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.keyFile = value
+			return nil
+		}},
+		"--get": {apply: func(cmd *curlCommand, _ string) error {
+			cmd.get = true
+			return nil
+		}},
+		"--head": {apply: func(cmd *curlCommand, _ string) error {
+			cmd.head = true
+			return nil
+		}},
+		// the transport asks for and decodes gzip by itself
+		"--compressed": ignore,
+		"--location": {apply: func(cmd *curlCommand, _ string) error {
+			cmd.location = true
+			return nil
+		}},
+		"--max-redirs": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			maxRedirects, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid curl --max-redirs %s", value)
+			}
+			cmd.clientOptions = append(cmd.clientOptions, WithMaxRedirects(maxRedirects))
+			return nil
+		}},
+		"--max-time": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				return fmt.Errorf("invalid curl --max-time %s", value)
+			}
+			cmd.clientOptions = append(cmd.clientOptions, WithTimeout(time.Duration(seconds*float64(time.Second))))
+			return nil
+		}},
+		"--proxy": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.clientOptions = append(cmd.clientOptions, WithProxy(value))
+			return nil
+		}},
+		"--noproxy": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.clientOptions = append(cmd.clientOptions, WithProxyBypass(value))
+			return nil
+		}},
+		"--resolve": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			host, port, addr, err := splitCurlHostPort(value)
+			if err != nil {
+				return fmt.Errorf("invalid curl --resolve %s", value)
+			}
+			addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+			cmd.clientOptions = append(cmd.clientOptions, WithResolveOverride(net.JoinHostPort(host, port), addr))
+			return nil
+		}},
+		"--connect-to": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			host, port, target, err := splitCurlHostPort(value)
+			if err != nil {
+				return fmt.Errorf("invalid curl --connect-to %s", value)
+			}
+			targetHost, targetPort, _, err := splitCurlHostPort(target + ":")
+			if err != nil {
+				return fmt.Errorf("invalid curl --connect-to %s", value)
+			}
+			cmd.clientOptions = append(cmd.clientOptions,
+				WithResolveOverride(net.JoinHostPort(host, port), net.JoinHostPort(targetHost, targetPort)))
+			return nil
+		}},
+		"--unix-socket": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.clientOptions = append(cmd.clientOptions, WithUnixSocket(value))
+			return nil
+		}},
+		"--user-agent": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.headers.Set("User-Agent", value)
+			return nil
+		}},
+		"--referer": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			cmd.headers.Set("Referer", value)
+			return nil
+		}},
+		"--cookie": {hasValue: true, apply: func(cmd *curlCommand, value string) error {
+			if !strings.Contains(value, "=") {
+				return fmt.Errorf("%w: reading cookies from file %s", ErrUnsupportedCurlOption, value)
+			}
+			cmd.headers.Add("Cookie", value)
+			return nil
+		}},
+		"--url": {hasValue: true, apply: (*curlCommand).setURL},
+
+		"--silent":     ignore,
+		"--show-error": ignore,
+		"--verbose":    ignore,
+		"--include":    ignore,
+		"--fail":       ignore,
+	}
+
+	shortNames := map[string]string{
+		"-X": "--request", "-H": "--header", "-d": "--data", "-F": "--form", "-u": "--user",
+		"-k": "--insecure", "-E": "--cert", "-G": "--get", "-I": "--head", "-L": "--location",
+		"-m": "--max-time", "-x": "--proxy", "-A": "--user-agent", "-e": "--referer", "-b": "--cookie",
+		"-s": "--silent", "-S": "--show-error", "-v": "--verbose", "-i": "--include", "-f": "--fail",
+	}
+	for short, long := range shortNames {
+		options[short] = options[long]
+	}
+	return options
+}
+
+func (cmd *curlCommand) parse(args []string) error {
+	options := curlOptions()
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			for _, rawURL := range args[i+1:] {
+				err := cmd.setURL(rawURL)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		case strings.HasPrefix(arg, "--"):
+			option, ok := options[arg]
+			if !ok {
+				return fmt.Errorf("%w %s", ErrUnsupportedCurlOption, arg)
+			}
+			value := ""
+			if option.hasValue {
+				i++
+				if i == len(args) {
+					return fmt.Errorf("curl option %s requires a value", arg)
+				}
+				value = args[i]
+			}
+			err := option.apply(cmd, value)
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// short options can be grouped, the last one may take a value
+			for j := 1; j < len(arg); j++ {
+				name := "-" + arg[j:j+1]
+				option, ok := options[name]
+				if !ok {
+					return fmt.Errorf("%w %s", ErrUnsupportedCurlOption, name)
+				}
+				value := ""
+				if option.hasValue {
+					value = arg[j+1:]
+					if value == "" {
+						i++
+						if i == len(args) {
+							return fmt.Errorf("curl option %s requires a value", name)
+						}
+						value = args[i]
+					}
+					j = len(arg)
+				}
+				err := option.apply(cmd, value)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			err := cmd.setURL(arg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if cmd.rawURL == "" {
+		return errors.New("curl command without url")
+	}
+	return nil
+}
+
+func (cmd *curlCommand) setURL(rawURL string) error {
+	if cmd.rawURL != "" {
+		return fmt.Errorf("%w: more than one url", ErrUnsupportedCurlOption)
+	}
+	cmd.rawURL = rawURL
+	return nil
+}
+
+// addHeader handles "Name: value", "Name:" which removes a header curl adds
+// by itself and "Name;" which sends an empty header.
+func (cmd *curlCommand) addHeader(value string) error {
+	if name, ok := strings.CutSuffix(value, ";"); ok && !strings.Contains(name, ":") {
+		cmd.headers.Add(name, "")
+		return nil
+	}
+
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid curl header %s", value)
+	}
+	headerValue = strings.TrimSpace(headerValue)
+
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	if headerValue == "" {
+		cmd.removed[name] = true
+		return nil
+	}
+	if name == "Host" {
+		cmd.host = headerValue
+		return nil
+	}
+	cmd.headers.Add(name, headerValue)
+	return nil
+}
+
+func (cmd *curlCommand) addURLEncodedData(value string) error {
+	escape := func(s string) string {
+		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+
+	name, content, ok := strings.Cut(value, "=")
+	switch {
+	case ok && name == "":
+		cmd.data = append(cmd.data, escape(content))
+	case ok:
+		cmd.data = append(cmd.data, name+"="+escape(content))
+	case strings.Contains(value, "@"):
+		return fmt.Errorf("%w: reading data from file %s", ErrUnsupportedCurlOption, value)
+	default:
+		cmd.data = append(cmd.data, escape(value))
+	}
+	return nil
+}
+
+func (cmd *curlCommand) formParams() *FormBodyParams {
+	if cmd.form == nil {
+		cmd.form = NewFormBodyParams(map[string]string{})
+	}
+	return cmd.form
+}
+
+func (cmd *curlCommand) addForm(value string) error {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("invalid curl form %s", value)
+	}
+
+	switch {
+	case strings.HasPrefix(content, "<"):
+		return fmt.Errorf("%w: reading form value from file %s", ErrUnsupportedCurlOption, value)
+	case strings.HasPrefix(content, "@"):
+		filename := content[1:]
+		if strings.Contains(filename, ";") {
+			return fmt.Errorf("%w: form file attributes %s", ErrUnsupportedCurlOption, value)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read form file error %w", err)
+		}
+		cmd.formParams().AddFile(name, filepath.Base(filename), bytes.NewReader(data))
+	default:
+		cmd.formParams().params[name] = content
+	}
+	return nil
+}
+
+// splitCurlHostPort splits the host:port:rest values of --resolve and
+// --connect-to, hosts may be in brackets.
+func splitCurlHostPort(value string) (host, port, rest string, err error) {
+	if strings.HasPrefix(value, "[") {
+		end := strings.Index(value, "]")
+		if end < 0 {
+			return "", "", "", errors.New("missing ]")
+		}
+		host, value = value[1:end], value[end+1:]
+		value, ok := strings.CutPrefix(value, ":")
+		if !ok {
+			return "", "", "", errors.New("missing port")
+		}
+		port, rest, _ = strings.Cut(value, ":")
+	} else {
+		var ok bool
+		host, value, ok = strings.Cut(value, ":")
+		if !ok {
+			return "", "", "", errors.New("missing port")
+		}
+		port, rest, _ = strings.Cut(value, ":")
+	}
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", "", err
+	}
+	return host, port, rest, nil
+}
+
+func (cmd *curlCommand) build() (*Client, *Request, error) {
+	rawURL := cmd.rawURL
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse curl url error %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil, fmt.Errorf("%w: scheme %s", ErrUnsupportedCurlOption, u.Scheme)
+	}
+	if len(cmd.data) != 0 && cmd.form != nil {
+		return nil, nil, errors.New("curl data and form can not be used together")
+	}
+
+	port := uint64(defaultPort)
+	if u.Port() != "" {
+		port, err = strconv.ParseUint(u.Port(), 10, 16)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid curl url port %s", u.Port())
+		}
+	} else if u.Scheme == "http" {
+		port = 80
+	}
+
+	clientOptions := []ClientOption{WithScheme(u.Scheme), WithPort(uint16(port))}
+	if !cmd.location {
+		clientOptions = append(clientOptions, WithoutRedirects())
+	}
+	clientOptions = append(clientOptions, cmd.clientOptions...)
+	if cmd.certFile != "" {
+		keyFile := cmd.keyFile
+		if keyFile == "" {
+			keyFile = cmd.certFile
+		}
+		clientOptions = append(clientOptions, WithClientCertificateFile(cmd.certFile, keyFile))
+	}
+	if u.User != nil && cmd.headers.Get(authorizationHeader) == "" {
+		password, _ := u.User.Password()
+		credentials := u.User.Username() + ":" + password
+		cmd.headers.Set(authorizationHeader, "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+
+	client, err := NewClient(u.Hostname(), clientOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := u.Query()
+	data := strings.Join(cmd.data, "&")
+	if cmd.get && len(cmd.data) != 0 {
+		dataQuery, err := url.ParseQuery(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse curl data as query error %w", err)
+		}
+		for key, values := range dataQuery {
+			query[key] = append(query[key], values...)
+		}
+	}
+
+	var requestOptions []RequestOption
+	if len(query) != 0 {
+		requestOptions = append(requestOptions, WithQueryParams(QueryParams(query)))
+	}
+	if cmd.host != "" {
+		requestOptions = append(requestOptions, WithHost(cmd.host))
+	}
+
+	// the content type is only sent when a Content-Type header is set
+	switch {
+	case cmd.form != nil:
+		if cmd.headers.Get(contentTypeHeader) == "" {
+			cmd.headers.Set(contentTypeHeader, "multipart/form-data")
+		}
+		requestOptions = append(requestOptions, WithBodyParams(cmd.form))
+	case len(cmd.data) != 0 && !cmd.get:
+		if cmd.headers.Get(contentTypeHeader) == "" && !cmd.removed[contentTypeHeader] {
+			cmd.headers.Set(contentTypeHeader, formURLEncodedContentType)
+		}
+		requestOptions = append(requestOptions,
+			WithBodyParams(NewRawBodyParams(cmd.headers.Get(contentTypeHeader), []byte(data))))
+	}
+
+	method := cmd.method
+	switch {
+	case method != "":
+	case cmd.head:
+		method = http.MethodHead
+	case cmd.get || (len(cmd.data) == 0 && cmd.form == nil):
+		method = http.MethodGet
+	default:
+		method = http.MethodPost
+	}
+
+	req, err := NewRequest(method, u.Path, requestOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range cmd.headers {
+		req.Headers[name] = values
+	}
+	return client, req, nil
+}
+
+// splitShellWords splits a command like a POSIX shell, with ANSI-C quoting,
+// without expanding variables.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, errors.New("trailing backslash in command")
+			}
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+				continue
+			}
+			word.WriteByte(s[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote in command")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			end, err := readANSICQuoted(s, i+2, &word)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			inWord = true
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) && strings.IndexByte("\"\\$`\n", s[j+1]) >= 0 {
+					j++
+					if s[j] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return nil, errors.New("unterminated double quote in command")
+			}
+			i = j
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// readANSICQuoted reads a $'...' string starting after the quote and returns
+// the index of the closing quote.
+func readANSICQuoted(s string, start int, word *strings.Builder) (int, error) {
+	escapes := map[byte]byte{
+		'a': '\a', 'b': '\b', 'e': 0x1b, 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+		'\\': '\\', '\'': '\'', '"': '"', '?': '?',
+	}
+
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			return i, nil
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			if b, ok := escapes[s[i]]; ok {
+				word.WriteByte(b)
+				continue
+			}
+			if s[i] == 'x' {
+				end := i + 1
+				for end < len(s) && end < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+					end++
+				}
+				if end > i+1 {
+					b, _ := strconv.ParseUint(s[i+1:end], 16, 8)
+					word.WriteByte(byte(b))
+					i = end - 1
+					continue
+				}
+			}
+			word.WriteByte('\\')
+			word.WriteByte(s[i])
+		default:
+			word.WriteByte(s[i])
+		}
+	}
+	return 0, errors.New("unterminated $' quote in command")
+}
@@ -0,0 +1,212 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{
+			name:    "get",
+			command: `curl https://api.example.com/api/test?q=1`,
+			want:    `curl --compressed 'https://api.example.com:443/api/test?q=1'`,
+		},
+		{
+			name: "post data with headers",
+			command: `curl -X POST 'http://api.example.com:8080/api/test' \
+  -H 'Content-Type: application/json' -H "X-Quote: it's" \
+  -d '{"msg":"hello"}'`,
+			want: `curl --compressed -H 'Content-Type: application/json' -H 'X-Quote: it'\''s' ` +
+				`--data-raw '{"msg":"hello"}' http://api.example.com:8080/api/test`,
+		},
+		{
+			name:    "data defaults to form urlencoded post",
+			command: `curl -L api.example.com/login -d user=amu --data-urlencode 'pass=a b&c'`,
+			want: `curl -L --max-redirs 10 --compressed -H 'Content-Type: application/x-www-form-urlencoded' ` +
+				`--data-raw 'user=amu&pass=a%20b%26c' http://api.example.com:80/login`,
+		},
+		{
+			name:    "get with data",
+			command: `curl -sSLG https://api.example.com/search -d q=go --data-urlencode 'tag=a b'`,
+			want:    `curl -L --max-redirs 10 --compressed 'https://api.example.com:443/search?q=go&tag=a+b'`,
+		},
+		{
+			name:    "basic auth and host header",
+			command: `curl -u amu:secret -H 'Host: virtual.example.com' -XPUT https://api.example.com/api/test`,
+			want: `curl -X PUT --compressed -H 'Host: virtual.example.com' ` +
+				`-H 'Authorization: Basic YW11OnNlY3JldA==' https://api.example.com:443/api/test`,
+		},
+		{
+			name: "client options",
+			command: `curl -k --compressed -L --max-redirs 3 -m 2.5 -x http://proxy:3128 --noproxy localhost ` +
+				`--resolve api.example.com:443:10.0.0.1 --connect-to 'api.example.com:8443:[::1]:443' -I ` +
+				`https://api.example.com/`,
+			want: `curl --head -L --max-redirs 3 --max-time 2.5 --compressed --insecure --proxy http://proxy:3128 ` +
+				`--noproxy localhost --resolve api.example.com:443:10.0.0.1 ` +
+				`--connect-to 'api.example.com:8443:[::1]:443' https://api.example.com:443/`,
+		},
+		{
+			name:    "removed content type",
+			command: `curl -X PUT -H 'Content-Type:' --data-binary $'line\none' http://api.example.com/`,
+			want: `curl -X PUT --compressed -H Content-Type: --data-raw 'line` + "\n" + `one' ` +
+				`http://api.example.com:80/`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, req, err := ParseCurl(tt.command)
+			if err != nil {
+				t.Errorf("ParseCurl() error = %v", err)
+				return
+			}
+			got, err := req.ToCurl(client)
+			if err != nil {
+				t.Errorf("ToCurl() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseCurl() ToCurl = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCurl_roundTrip(t *testing.T) {
+	client, _ := NewClient("api.example.com", WithTimeout(3*time.Second), WithResolveOverride("api.example.com:443", "10.0.0.1"))
+	req, _ := NewRequest(
+		http.MethodPatch, "/api/items/1",
+		WithQueryParams(NewQueryParams(map[string]string{"fields": "a,b"})),
+		WithHeaders(map[string]string{"Content-Type": "application/json", "X-Binary": "a\x01b"}),
+		WithBodyParams(NewJsonBodyParams(map[string]string{"name": "it's"})),
+	)
+
+	command, err := req.ToCurl(client)
+	if err != nil {
+		t.Errorf("ToCurl() error = %v", err)
+		return
+	}
+	parsedClient, parsedReq, err := ParseCurl(command)
+	if err != nil {
+		t.Errorf("ParseCurl() error = %v", err)
+		return
+	}
+	got, _ := parsedReq.ToCurl(parsedClient)
+	if got != command {
+		t.Errorf("ParseCurl() ToCurl = %v, want %v", got, command)
+	}
+}
+
+func TestParseCurl_do(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "avatar.png")
+	_ = os.WriteFile(file, []byte("png"), 0o600)
+
+	type received struct {
+		method string
+		user   string
+		pass   string
+		lang   string
+		avatar string
+	}
+	var got received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method = r.Method
+		got.user, got.pass, _ = r.BasicAuth()
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got.lang = r.FormValue("lang")
+		if f, _, err := r.FormFile("avatar"); err == nil {
+			data, _ := io.ReadAll(f)
+			got.avatar = string(data)
+		}
+	}))
+	defer server.Close()
+
+	client, req, err := ParseCurl(`curl -u amu:secret -F lang=go -F 'avatar=@` + file + `' ` + server.URL + `/upload`)
+	if err != nil {
+		t.Errorf("ParseCurl() error = %v", err)
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Do() = %v, %v", resp, err)
+		return
+	}
+	want := received{method: http.MethodPost, user: "amu", pass: "secret", lang: "go", avatar: "png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Do() received = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCurl_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		wantErr error
+	}{
+		{name: "not curl", command: `wget https://api.example.com`},
+		{name: "unsupported option", command: `curl --http3 https://api.example.com`, wantErr: ErrUnsupportedCurlOption},
+		{name: "unsupported short option", command: `curl -Z https://api.example.com`, wantErr: ErrUnsupportedCurlOption},
+		{name: "data from file", command: `curl -d @body.json https://api.example.com`, wantErr: ErrUnsupportedCurlOption},
+		{name: "user without password", command: `curl -u amu https://api.example.com`, wantErr: ErrUnsupportedCurlOption},
+		{name: "multiple urls", command: `curl https://a.example.com https://b.example.com`, wantErr: ErrUnsupportedCurlOption},
+		{name: "unsupported scheme", command: `curl ftp://api.example.com`, wantErr: ErrUnsupportedCurlOption},
+		{name: "missing value", command: `curl https://api.example.com -H`},
+		{name: "missing url", command: `curl -X GET`},
+		{name: "unterminated quote", command: `curl 'https://api.example.com`},
+		{name: "data and form", command: `curl -d a=b -F c=d https://api.example.com`},
+		{name: "invalid header", command: `curl -H nothing https://api.example.com`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseCurl(tt.command)
+			if err == nil {
+				t.Errorf("ParseCurl() error = %v, wantErr true", err)
+				return
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseCurl() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_splitShellWords(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{name: "plain", s: "curl  -s\thttp://a", want: []string{"curl", "-s", "http://a"}},
+		{name: "single quotes", s: `curl 'a b' 'it'\''s'`, want: []string{"curl", "a b", "it's"}},
+		{name: "double quotes", s: `curl "a \"b\" \$c \d"`, want: []string{"curl", `a "b" $c \d`}},
+		{name: "ansi-c quotes", s: `curl $'a\nb\x00\'c'`, want: []string{"curl", "a\nb\x00'c"}},
+		{name: "line continuation", s: "curl \\\n  -s \\\r\n  http://a", want: []string{"curl", "-s", "http://a"}},
+		{name: "empty argument", s: `curl ''`, want: []string{"curl", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShellWords(tt.s)
+			if err != nil {
+				t.Errorf("splitShellWords() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShellWords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequest_ToCurl(t *testing.T) {
+	form := NewFormBodyParams(map[string]string{"name": "@amu", "lang": "go"})
+	form.AddFile("avatar", "avatar.png", strings.NewReader("png"))
+
+	tests := []struct {
+		name    string
+		client  []ClientOption
+		method  string
+		options []RequestOption
+		want    string
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			options: []RequestOption{
+				WithQueryParams(NewQueryParams(map[string]string{"q": "it's"})),
+				WithHeaders(map[string]string{"X-Token": "a b"}),
+			},
+			want: `curl -L --max-redirs 10 --compressed -H 'X-Token: a b' 'https://api.example.com:443/api/test?q=it%27s'`,
+		},
+		{
+			name:   "post json with host",
+			method: http.MethodPost,
+			options: []RequestOption{
+				WithHost("virtual.example.com"),
+				WithHeaders(map[string]string{"Content-Type": "application/json"}),
+				WithBodyParams(NewJsonBodyParams(map[string]string{"msg": "it's"})),
+			},
+			want: `curl -L --max-redirs 10 --compressed -H 'Host: virtual.example.com' ` +
+				`-H 'Content-Type: application/json; charset=UTF-8' --data-raw '{"msg":"it'\''s"}' ` +
+				`https://api.example.com:443/api/test`,
+		},
+		{
+			name:    "put json without content type",
+			method:  http.MethodPut,
+			options: []RequestOption{WithBodyParams(NewJsonBodyParams([]int{1}))},
+			want: `curl -X PUT -L --max-redirs 10 --compressed -H Content-Type: --data-raw '[1]' ` +
+				`https://api.example.com:443/api/test`,
+		},
+		{
+			name:   "body starting with at sign",
+			method: http.MethodPost,
+			options: []RequestOption{
+				WithHeaders(map[string]string{"Content-Type": "text/plain"}),
+				WithBodyParams(NewRawBodyParams("text/plain", []byte("@/etc/passwd"))),
+			},
+			want: `curl -L --max-redirs 10 --compressed -H 'Content-Type: text/plain' --data-raw @/etc/passwd ` +
+				`https://api.example.com:443/api/test`,
+		},
+		{
+			name:    "multipart form",
+			method:  http.MethodPost,
+			options: []RequestOption{WithBodyParams(form)},
+			want: `curl -L --max-redirs 10 --compressed --form-string lang=go --form-string name=@amu ` +
+				`-F avatar=@avatar.png https://api.example.com:443/api/test`,
+		},
+		{
+			name:   "client options",
+			method: http.MethodHead,
+			client: []ClientOption{
+				WithoutRedirects(),
+				WithTimeout(1500 * time.Millisecond),
+				WithSkipVerifyCertificates(),
+				WithProxy("http://proxy.example.com:3128"),
+				WithProxyBypass("localhost", ".internal"),
+				WithResolveOverride("api.example.com:443", "10.0.0.1"),
+				WithResolveOverride("other.example.com:443", "[::1]:8443"),
+			},
+			want: `curl --head --max-time 1.5 --compressed --insecure --proxy http://proxy.example.com:3128 ` +
+				`--noproxy localhost,.internal --resolve api.example.com:443:10.0.0.1 ` +
+				`--connect-to 'other.example.com:443:[::1]:8443' https://api.example.com:443/api/test`,
+		},
+		{
+			name:   "unix socket",
+			method: http.MethodDelete,
+			client: []ClientOption{WithScheme("http"), WithPort(80), WithUnixSocket("/var/run/docker.sock")},
+			want: `curl -X DELETE -L --max-redirs 10 --compressed --unix-socket /var/run/docker.sock ` +
+				`http://api.example.com:80/api/test`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient("api.example.com", tt.client...)
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+			req, _ := NewRequest(tt.method, "/api/test", tt.options...)
+			got, err := req.ToCurl(client)
+			if err != nil {
+				t.Errorf("ToCurl() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToCurl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest_ToCurl_certificate(t *testing.T) {
+	client, _ := NewClient("api.example.com")
+	client.certificateFiles = []certificateFile{{certFile: "client.crt", keyFile: "client key.pem"}}
+	req, _ := NewRequest(http.MethodGet, "/")
+
+	got, _ := req.ToCurl(client)
+	if !strings.Contains(got, `--cert client.crt --key 'client key.pem'`) {
+		t.Errorf("ToCurl() = %v, want certificate flags", got)
+	}
+}
+
+func Test_shellQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "safe", s: "-H", want: "-H"},
+		{name: "empty", s: "", want: "''"},
+		{name: "single quote", s: "it's $HOME", want: `'it'\''s $HOME'`},
+		{name: "binary", s: "a\x00'b\xff", want: `$'a\x00\'b\xff'`},
+	}
+	sh, err := exec.LookPath("sh")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shellQuote(tt.s)
+			if got != tt.want {
+				t.Errorf("shellQuote() = %v, want %v", got, tt.want)
+			}
+			if err != nil || strings.HasPrefix(got, "$'") {
+				// ANSI-C quoting is not POSIX sh
+				return
+			}
+			out, runErr := exec.Command(sh, "-c", "printf %s "+got).Output()
+			if runErr != nil || string(out) != tt.s {
+				t.Errorf("sh printf %s = %q, %v, want %q", got, out, runErr, tt.s)
+			}
+		})
+	}
+}
+
+func TestRequest_ToCurl_run(t *testing.T) {
+	curl, err := exec.LookPath("curl")
+	if err != nil {
+		t.Skip("curl not installed")
+	}
+
+	var got []byte
+	var gotHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server)
+	req, _ := NewRequest(
+		http.MethodPatch, "/api/test",
+		WithHeaders(map[string]string{"X-Quote": `it's "quoted"`}),
+		WithBodyParams(NewJsonBodyParams(map[string]string{"msg": "it's\nmultiline"})),
+	)
+	command, err := req.ToCurl(client)
+	if err != nil {
+		t.Errorf("ToCurl() error = %v", err)
+		return
+	}
+
+	out, err := exec.Command("sh", "-c", strings.Replace(command, "curl", curl+" -sS", 1)).CombinedOutput()
+	if err != nil {
+		t.Errorf("curl error = %v, %s", err, out)
+		return
+	}
+	if want := "{\"msg\":\"it's\\nmultiline\"}"; !bytes.Equal(got, []byte(want)) {
+		t.Errorf("curl body = %s, want %s", got, want)
+	}
+	if gotHeader.Get("X-Quote") != `it's "quoted"` || gotHeader.Get("Content-Type") != "" {
+		t.Errorf("curl header = %v", gotHeader)
+	}
+}
+
+func TestRequest_ToCurl_runAtSign(t *testing.T) {
+	curl, err := exec.LookPath("curl")
+	if err != nil {
+		t.Skip("curl not installed")
+	}
+
+	var got []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server)
+	req, _ := NewRequest(
+		http.MethodPost, "/api/test",
+		WithHeaders(map[string]string{"Content-Type": "text/plain"}),
+		WithBodyParams(NewRawBodyParams("text/plain", []byte("@/etc/hostname"))),
+	)
+	command, err := req.ToCurl(client)
+	if err != nil {
+		t.Errorf("ToCurl() error = %v", err)
+		return
+	}
+
+	out, err := exec.Command("sh", "-c", strings.Replace(command, "curl", curl+" -sS", 1)).CombinedOutput()
+	if err != nil {
+		t.Errorf("curl error = %v, %s", err, out)
+		return
+	}
+	if string(got) != "@/etc/hostname" {
+		t.Errorf("curl body = %s, want @/etc/hostname", got)
+	}
+}
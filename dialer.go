@@ -0,0 +1,100 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialer replaces how connections are opened, the request URL and Host
+// header still come from the client and request.
+func WithDialer(dial DialContextFunc) ClientOption {
+	return func(c *Client) error {
+		c.unixSocket = ""
+		if c.dns != nil {
+			c.dns.next = dial
+			return nil
+		}
+		c.transport.DialContext = dial
+		return nil
+	}
+}
+
+func WithUnixSocket(socketPath string) ClientOption {
+	dial := WithDialer(func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socketPath)
+	})
+	return func(c *Client) error {
+		err := dial(c)
+		c.unixSocket = socketPath
+		return err
+	}
+}
+
+// MemoryListener is a net.Listener whose connections are made in memory by
+// its DialContext method, useful to serve a handler without opening a port.
+type MemoryListener struct {
+	conns chan net.Conn
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var ErrMemoryListenerClosed = errors.New("memory listener closed")
+
+func NewMemoryListener() *MemoryListener {
+	return &MemoryListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *MemoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, ErrMemoryListenerClosed
+	}
+}
+
+func (l *MemoryListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *MemoryListener) Addr() net.Addr {
+	return memoryAddr{}
+}
+
+func (l *MemoryListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		_ = server.Close()
+		_ = client.Close()
+		return nil, ErrMemoryListenerClosed
+	case <-ctx.Done():
+		_ = server.Close()
+		_ = client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+type memoryAddr struct{}
+
+func (memoryAddr) Network() string {
+	return "memory"
+}
+
+func (memoryAddr) String() string {
+	return "memory"
+}
@@ -0,0 +1,117 @@
+package request
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix socket not supported: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Path", r.URL.Path)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		options  []RequestOption
+		wantHost string
+	}{
+		{
+			name:     "request through unix socket",
+			options:  nil,
+			wantHost: "docker:80",
+		},
+		{
+			name:     "request through unix socket with host",
+			options:  []RequestOption{WithHost("api.docker")},
+			wantHost: "api.docker",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := NewClient("docker", WithScheme("http"), WithPort(80), WithUnixSocket(socketPath))
+			req, _ := NewRequest(http.MethodGet, "/v1.41/containers/json", tt.options...)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if got := resp.Header.Get("X-Host"); got != tt.wantHost {
+				t.Errorf("Do() Host = %v, want %v", got, tt.wantHost)
+			}
+			if got := resp.Header.Get("X-Path"); got != "/v1.41/containers/json" {
+				t.Errorf("Do() Path = %v, want %v", got, "/v1.41/containers/json")
+			}
+		})
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	listener := NewMemoryListener()
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	var dialedAddr string
+	client, _ := NewClient(
+		"sidecar",
+		WithScheme("http"),
+		WithPort(8080),
+		WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialedAddr = addr
+			return listener.DialContext(ctx, network, addr)
+		}),
+	)
+	req, _ := NewRequest(http.MethodGet, "/api/test")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Do() StatusCode = %v, want %v", resp.StatusCode, http.StatusAccepted)
+	}
+	if dialedAddr != "sidecar:8080" {
+		t.Errorf("WithDialer() addr = %v, want %v", dialedAddr, "sidecar:8080")
+	}
+}
+
+func TestMemoryListener_Close(t *testing.T) {
+	listener := NewMemoryListener()
+	_ = listener.Close()
+
+	_, err := listener.Accept()
+	if err != ErrMemoryListenerClosed {
+		t.Errorf("Accept() error = %v, want %v", err, ErrMemoryListenerClosed)
+	}
+	_, err = listener.DialContext(context.Background(), "tcp", "memory")
+	if err != ErrMemoryListenerClosed {
+		t.Errorf("DialContext() error = %v, want %v", err, ErrMemoryListenerClosed)
+	}
+}
+
+func TestMemoryListener_DialContext_canceled(t *testing.T) {
+	listener := NewMemoryListener()
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := listener.DialContext(ctx, "tcp", "memory")
+	if err != context.Canceled {
+		t.Errorf("DialContext() error = %v, want %v", err, context.Canceled)
+	}
+}
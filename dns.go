@@ -0,0 +1,275 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// HostResolver looks up the addresses of a host with the time they can be
+// cached for. A zero ttl uses the default TTL of the cache.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) (addrs []string, ttl time.Duration, err error)
+}
+
+type netHostResolver struct {
+	resolver *net.Resolver
+}
+
+func (r *netHostResolver) LookupHost(ctx context.Context, host string) ([]string, time.Duration, error) {
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	return addrs, 0, err
+}
+
+type DNSCache struct {
+	resolver HostResolver
+
+	ttl         time.Duration
+	negativeTTL time.Duration
+	staleTTL    time.Duration
+
+	mu         sync.Mutex
+	entries    map[string]*dnsCacheEntry
+	refreshing map[string]bool
+
+	now func() time.Time
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+type DNSCacheOption func(*DNSCache) error
+
+const (
+	defaultDNSCacheTTL         = time.Minute
+	defaultDNSCacheNegativeTTL = 5 * time.Second
+	dnsRefreshTimeout          = 10 * time.Second
+)
+
+func NewDNSCache(options ...DNSCacheOption) (cache *DNSCache, err error) {
+	cache = &DNSCache{
+		resolver: &netHostResolver{resolver: net.DefaultResolver},
+
+		ttl:         defaultDNSCacheTTL,
+		negativeTTL: defaultDNSCacheNegativeTTL,
+
+		entries:    map[string]*dnsCacheEntry{},
+		refreshing: map[string]bool{},
+
+		now: time.Now,
+	}
+
+	for _, option := range options {
+		err = option(cache)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// WithDNSCacheTTL sets how long addresses are cached when the resolver does
+// not tell, the system resolver never does.
+func WithDNSCacheTTL(ttl time.Duration) DNSCacheOption {
+	return func(c *DNSCache) error {
+		if ttl <= 0 {
+			return fmt.Errorf("invalid dns cache ttl %s", ttl)
+		}
+		c.ttl = ttl
+		return nil
+	}
+}
+
+// WithDNSCacheNegativeTTL sets how long a failed lookup is cached, zero
+// disables negative caching.
+func WithDNSCacheNegativeTTL(ttl time.Duration) DNSCacheOption {
+	return func(c *DNSCache) error {
+		if ttl < 0 {
+			return fmt.Errorf("invalid dns cache negative ttl %s", ttl)
+		}
+		c.negativeTTL = ttl
+		return nil
+	}
+}
+
+// WithDNSCacheStaleTTL keeps serving expired addresses for up to staleTTL
+// while they are looked up again in background.
+func WithDNSCacheStaleTTL(staleTTL time.Duration) DNSCacheOption {
+	return func(c *DNSCache) error {
+		if staleTTL < 0 {
+			return fmt.Errorf("invalid dns cache stale ttl %s", staleTTL)
+		}
+		c.staleTTL = staleTTL
+		return nil
+	}
+}
+
+func WithDNSCacheResolver(resolver HostResolver) DNSCacheOption {
+	return func(c *DNSCache) error {
+		if resolver == nil {
+			return errors.New("dns cache resolver is nil")
+		}
+		c.resolver = resolver
+		return nil
+	}
+}
+
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	now := c.now()
+	if ok && now.Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.addrs, entry.err
+	}
+	if ok && entry.err == nil && now.Before(entry.expires.Add(c.staleTTL)) {
+		if !c.refreshing[host] {
+			c.refreshing[host] = true
+			go c.refresh(host)
+		}
+		c.mu.Unlock()
+		return entry.addrs, nil
+	}
+	c.mu.Unlock()
+
+	return c.lookup(ctx, host)
+}
+
+func (c *DNSCache) refresh(host string) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsRefreshTimeout)
+	defer cancel()
+
+	_, _ = c.lookup(ctx, host)
+
+	c.mu.Lock()
+	delete(c.refreshing, host)
+	c.mu.Unlock()
+}
+
+func (c *DNSCache) lookup(ctx context.Context, host string) ([]string, error) {
+	addrs, ttl, err := c.resolver.LookupHost(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.removeExpired(now)
+	switch {
+	case err == nil:
+		if ttl <= 0 {
+			ttl = c.ttl
+		}
+		c.entries[host] = &dnsCacheEntry{addrs: addrs, expires: now.Add(ttl)}
+	case ctx.Err() != nil:
+		// a canceled lookup says nothing about the host
+	case c.negativeTTL > 0:
+		c.entries[host] = &dnsCacheEntry{err: err, expires: now.Add(c.negativeTTL)}
+	}
+
+	return addrs, err
+}
+
+// removeExpired drops the entries that can no longer be served, so hosts
+// dialed once do not stay cached.
+func (c *DNSCache) removeExpired(now time.Time) {
+	for host, entry := range c.entries {
+		expires := entry.expires
+		if entry.err == nil {
+			expires = expires.Add(c.staleTTL)
+		}
+		if !now.Before(expires) {
+			delete(c.entries, host)
+		}
+	}
+}
+
+type dnsDialer struct {
+	cache     *DNSCache
+	overrides map[string]string
+	next      DialContextFunc
+}
+
+func (c *Client) dnsDialer() *dnsDialer {
+	if c.dns == nil {
+		next := c.transport.DialContext
+		if next == nil {
+			var dialer net.Dialer
+			next = dialer.DialContext
+		}
+		c.dns = &dnsDialer{
+			overrides: map[string]string{},
+			next:      next,
+		}
+		c.transport.DialContext = c.dns.dialContext
+	}
+	return c.dns
+}
+
+// WithDNSCache caches the addresses of hosts dialed by the client, a nil cache
+// uses NewDNSCache defaults.
+func WithDNSCache(cache *DNSCache) ClientOption {
+	return func(c *Client) (err error) {
+		if cache == nil {
+			cache, err = NewDNSCache()
+			if err != nil {
+				return
+			}
+		}
+		c.dnsDialer().cache = cache
+		return
+	}
+}
+
+// WithResolveOverride dials addr instead of hostPort, like curl --resolve. The
+// URL, Host header and TLS server name are unchanged.
+func WithResolveOverride(hostPort, addr string) ClientOption {
+	return func(c *Client) error {
+		_, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			return fmt.Errorf("invalid resolve override host %s: %w", hostPort, err)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, port)
+		}
+		c.dnsDialer().overrides[hostPort] = addr
+		return nil
+	}
+}
+
+func (d *dnsDialer) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if override, ok := d.overrides[addr]; ok {
+		addr = override
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || d.cache == nil || net.ParseIP(host) != nil {
+		return d.next(ctx, network, addr)
+	}
+
+	addrs, err := d.cache.LookupHost(ctx, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	for _, ip := range addrs {
+		var conn net.Conn
+		conn, err = d.next(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,238 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testHostResolver struct {
+	mu      sync.Mutex
+	lookups int
+	addrs   []string
+	ttl     time.Duration
+	err     error
+}
+
+func (r *testHostResolver) LookupHost(_ context.Context, _ string) ([]string, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	return r.addrs, r.ttl, r.err
+}
+
+func (r *testHostResolver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups
+}
+
+func TestDNSCache_LookupHost(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name        string
+		resolver    *testHostResolver
+		options     []DNSCacheOption
+		elapsed     time.Duration
+		wantAddrs   []string
+		wantErr     bool
+		wantLookups int
+	}{
+		{
+			name:        "cached addresses",
+			resolver:    &testHostResolver{addrs: []string{"10.0.0.1"}},
+			elapsed:     30 * time.Second,
+			wantAddrs:   []string{"10.0.0.1"},
+			wantLookups: 1,
+		},
+		{
+			name:        "expired addresses",
+			resolver:    &testHostResolver{addrs: []string{"10.0.0.1"}},
+			elapsed:     2 * time.Minute,
+			wantAddrs:   []string{"10.0.0.1"},
+			wantLookups: 2,
+		},
+		{
+			name:        "resolver ttl",
+			resolver:    &testHostResolver{addrs: []string{"10.0.0.1"}, ttl: 5 * time.Minute},
+			elapsed:     2 * time.Minute,
+			wantAddrs:   []string{"10.0.0.1"},
+			wantLookups: 1,
+		},
+		{
+			name:        "negative cache",
+			resolver:    &testHostResolver{err: errors.New("no such host")},
+			elapsed:     time.Second,
+			wantErr:     true,
+			wantLookups: 1,
+		},
+		{
+			name:        "negative cache disabled",
+			resolver:    &testHostResolver{err: errors.New("no such host")},
+			options:     []DNSCacheOption{WithDNSCacheNegativeTTL(0)},
+			elapsed:     time.Second,
+			wantErr:     true,
+			wantLookups: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, err := NewDNSCache(append([]DNSCacheOption{WithDNSCacheResolver(tt.resolver)}, tt.options...)...)
+			if err != nil {
+				t.Errorf("NewDNSCache() error = %v", err)
+				return
+			}
+			cache.now = func() time.Time { return now }
+			_, _ = cache.LookupHost(context.Background(), "example.com")
+
+			cache.now = func() time.Time { return now.Add(tt.elapsed) }
+			gotAddrs, err := cache.LookupHost(context.Background(), "example.com")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LookupHost() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotAddrs, tt.wantAddrs) {
+				t.Errorf("LookupHost() = %v, want %v", gotAddrs, tt.wantAddrs)
+			}
+			if got := tt.resolver.count(); got != tt.wantLookups {
+				t.Errorf("LookupHost() lookups = %v, want %v", got, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestDNSCache_staleWhileRevalidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	resolver := &testHostResolver{addrs: []string{"10.0.0.1"}}
+	cache, _ := NewDNSCache(WithDNSCacheResolver(resolver), WithDNSCacheStaleTTL(time.Minute))
+	cache.now = func() time.Time { return now }
+	_, _ = cache.LookupHost(context.Background(), "example.com")
+
+	resolver.mu.Lock()
+	resolver.addrs = []string{"10.0.0.2"}
+	resolver.mu.Unlock()
+	cache.now = func() time.Time { return now.Add(90 * time.Second) }
+
+	got, err := cache.LookupHost(context.Background(), "example.com")
+	if err != nil || !reflect.DeepEqual(got, []string{"10.0.0.1"}) {
+		t.Errorf("LookupHost() = %v, %v, want stale %v", got, err, []string{"10.0.0.1"})
+		return
+	}
+
+	ok := waitFor(t, func() bool {
+		got, _ := cache.LookupHost(context.Background(), "example.com")
+		return reflect.DeepEqual(got, []string{"10.0.0.2"})
+	})
+	if !ok {
+		t.Errorf("LookupHost() not revalidated")
+	}
+}
+
+func TestWithResolveOverride(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Server-Name", r.TLS.ServerName)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	port, _ := strconv.ParseUint(serverURL.Port(), 10, 16)
+	hostPort := net.JoinHostPort("example.com", serverURL.Port())
+
+	tests := []struct {
+		name    string
+		options []ClientOption
+	}{
+		{
+			name:    "resolve override",
+			options: []ClientOption{WithResolveOverride(hostPort, serverURL.Host)},
+		},
+		{
+			name:    "resolve override without port",
+			options: []ClientOption{WithResolveOverride(hostPort, "127.0.0.1")},
+		},
+		{
+			name: "dns cache",
+			options: []ClientOption{
+				WithDNSCache(mustDNSCache(WithDNSCacheResolver(&testHostResolver{addrs: []string{"127.0.0.1"}}))),
+			},
+		},
+		{
+			name: "resolve override with dns cache",
+			options: []ClientOption{
+				WithDNSCache(nil),
+				WithResolveOverride(hostPort, "localhost:"+serverURL.Port()),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := server.Client().Transport.(*http.Transport).Clone()
+			options := append([]ClientOption{WithTransport(transport), WithPort(uint16(port))}, tt.options...)
+			client, err := NewClient("example.com", options...)
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+
+			req, _ := NewRequest(http.MethodGet, "/api/test")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if got := resp.Header.Get("X-Host"); got != hostPort {
+				t.Errorf("Do() Host = %v, want %v", got, hostPort)
+			}
+			if got := resp.Header.Get("X-Server-Name"); got != "example.com" {
+				t.Errorf("Do() ServerName = %v, want %v", got, "example.com")
+			}
+		})
+	}
+}
+
+func TestWithResolveOverride_invalid(t *testing.T) {
+	_, err := NewClient("example.com", WithResolveOverride("example.com", "127.0.0.1"))
+	if err == nil {
+		t.Errorf("WithResolveOverride() error = %v, wantErr true", err)
+	}
+}
+
+func mustDNSCache(options ...DNSCacheOption) *DNSCache {
+	cache, err := NewDNSCache(options...)
+	if err != nil {
+		panic(err)
+	}
+	return cache
+}
+
+func TestDNSCache_removeExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	resolver := &testHostResolver{addrs: []string{"10.0.0.1"}, ttl: time.Minute}
+	cache, _ := NewDNSCache(WithDNSCacheResolver(resolver), WithDNSCacheStaleTTL(time.Minute))
+	cache.now = func() time.Time { return now }
+	_, _ = cache.LookupHost(context.Background(), "stale.example.com")
+	resolver.err = errors.New("no such host")
+	_, _ = cache.LookupHost(context.Background(), "missing.example.com")
+	resolver.err = nil
+
+	cache.now = func() time.Time { return now.Add(90 * time.Second) }
+	_, _ = cache.LookupHost(context.Background(), "new.example.com")
+	if got := len(cache.entries); got != 2 {
+		t.Errorf("entries = %v, want stale and new", got)
+	}
+
+	cache.now = func() time.Time { return now.Add(3 * time.Minute) }
+	_, _ = cache.LookupHost(context.Background(), "other.example.com")
+	if _, ok := cache.entries["stale.example.com"]; ok || len(cache.entries) != 2 {
+		t.Errorf("entries = %v, want new and other", cache.entries)
+	}
+}
@@ -0,0 +1,339 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Fault replaces a round trip, it may call next to send the request.
+type Fault func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error)
+
+// LatencyFault delays the request by latency.
+func LatencyFault(latency time.Duration) Fault {
+	return func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error) {
+		err := sleep(httpRequest, latency)
+		if err != nil {
+			closeRequestBody(httpRequest)
+			return nil, err
+		}
+		return next.RoundTrip(httpRequest)
+	}
+}
+
+// ConnectionResetFault fails the request with a connection reset by peer.
+func ConnectionResetFault() Fault {
+	return func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error) {
+		closeRequestBody(httpRequest)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	}
+}
+
+// TimeoutFault fails the request with a timeout error after waiting.
+func TimeoutFault(after time.Duration) Fault {
+	return func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error) {
+		closeRequestBody(httpRequest)
+		err := sleep(httpRequest, after)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: faultTimeoutError{}}
+	}
+}
+
+// TruncatedBodyFault cuts the response body after size bytes, reading it
+// further fails with io.ErrUnexpectedEOF. Shorter bodies are not changed.
+func TruncatedBodyFault(size int) Fault {
+	return func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error) {
+		httpResponse, err := next.RoundTrip(httpRequest)
+		if err != nil {
+			return nil, err
+		}
+		httpResponse.Body = &truncatedBody{body: httpResponse.Body, remaining: int64(size)}
+		return httpResponse, nil
+	}
+}
+
+// StatusFault answers the request with statusCode without sending it.
+func StatusFault(statusCode int) Fault {
+	return func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error) {
+		closeRequestBody(httpRequest)
+		httpResponse := newHTTPResponse(statusCode, nil, nil)
+		httpResponse.Request = httpRequest
+		return httpResponse, nil
+	}
+}
+
+// MalformedJSONFault keeps the first half of the response body so that it is
+// no longer valid JSON.
+func MalformedJSONFault() Fault {
+	return func(next http.RoundTripper, httpRequest *http.Request) (*http.Response, error) {
+		httpResponse, err := next.RoundTrip(httpRequest)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(httpResponse.Body)
+		_ = httpResponse.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(body) < 2 {
+			body = []byte("{")
+		} else {
+			body = body[:len(body)/2]
+		}
+		httpResponse.Body = io.NopCloser(bytes.NewReader(body))
+		httpResponse.ContentLength = int64(len(body))
+		httpResponse.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return httpResponse, nil
+	}
+}
+
+func sleep(httpRequest *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-httpRequest.Context().Done():
+		return httpRequest.Context().Err()
+	}
+}
+
+func closeRequestBody(httpRequest *http.Request) {
+	if httpRequest.Body != nil {
+		_ = httpRequest.Body.Close()
+	}
+}
+
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return "i/o timeout" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// the body is only cut when it goes on
+		var next [1]byte
+		for {
+			n, err := b.body.Read(next[:])
+			if n > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
+
+type FaultInjectorOption func(*FaultInjector) error
+
+type FaultRuleOption func(*faultRule) error
+
+// FaultInjector injects faults into the requests matching its rules. The
+// first rule that matches a request and triggers injects its fault.
+type FaultInjector struct {
+	rules []*faultRule
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+type faultRule struct {
+	fault       Fault
+	host        string
+	path        string
+	probability float64
+	schedule    []bool
+	count       int
+}
+
+func NewFaultInjector(options ...FaultInjectorOption) (injector *FaultInjector, err error) {
+	injector = &FaultInjector{
+		random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+
+	for _, option := range options {
+		err = option(injector)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// WithFaultSeed seeds the random numbers used for probabilities, to make them
+// reproducible.
+func WithFaultSeed(seed int64) FaultInjectorOption {
+	return func(i *FaultInjector) error {
+		i.random = rand.New(rand.NewPCG(uint64(seed), 0))
+		return nil
+	}
+}
+
+// WithFaultRule injects fault into every matching request, unless a
+// probability or schedule is given.
+func WithFaultRule(fault Fault, options ...FaultRuleOption) FaultInjectorOption {
+	return func(i *FaultInjector) error {
+		if fault == nil {
+			return fmt.Errorf("fault is nil")
+		}
+		rule := &faultRule{fault: fault, probability: 1}
+		for _, option := range options {
+			err := option(rule)
+			if err != nil {
+				return err
+			}
+		}
+		i.rules = append(i.rules, rule)
+		return nil
+	}
+}
+
+// WithFaultHost scopes the rule to hosts matching pattern, with the syntax of
+// path.Match.
+func WithFaultHost(pattern string) FaultRuleOption {
+	return func(r *faultRule) error {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid fault host pattern %s", pattern)
+		}
+		r.host = pattern
+		return nil
+	}
+}
+
+// WithFaultPath scopes the rule to paths matching pattern, with the syntax of
+// path.Match.
+func WithFaultPath(pattern string) FaultRuleOption {
+	return func(r *faultRule) error {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid fault path pattern %s", pattern)
+		}
+		r.path = pattern
+		return nil
+	}
+}
+
+func WithFaultProbability(probability float64) FaultRuleOption {
+	return func(r *faultRule) error {
+		if probability < 0 || probability > 1 {
+			return fmt.Errorf("invalid fault probability %v", probability)
+		}
+		r.probability = probability
+		return nil
+	}
+}
+
+// WithFaultSchedule triggers the rule by the nth matching request following
+// schedule, which repeats. For example true, false, false injects the fault
+// into the first of every three requests.
+func WithFaultSchedule(schedule ...bool) FaultRuleOption {
+	return func(r *faultRule) error {
+		if len(schedule) == 0 {
+			return fmt.Errorf("fault schedule is empty")
+		}
+		r.schedule = schedule
+		return nil
+	}
+}
+
+// WithFaultInjector injects the faults of injector into the requests of the
+// client.
+func WithFaultInjector(injector *FaultInjector) ClientOption {
+	return func(c *Client) error {
+		if injector == nil {
+			return fmt.Errorf("fault injector is nil")
+		}
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &faultTransport{injector: injector, next: next}
+		})
+		return nil
+	}
+}
+
+func (i *FaultInjector) fault(httpRequest *http.Request) Fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, rule := range i.rules {
+		if !rule.match(httpRequest) {
+			continue
+		}
+
+		triggered := true
+		if len(rule.schedule) != 0 {
+			triggered = rule.schedule[rule.count%len(rule.schedule)]
+		} else if rule.probability < 1 {
+			triggered = i.random.Float64() < rule.probability
+		}
+		rule.count++
+
+		if triggered {
+			return rule.fault
+		}
+	}
+	return nil
+}
+
+func (r *faultRule) match(httpRequest *http.Request) bool {
+	if r.host != "" {
+		if ok, _ := path.Match(r.host, httpRequest.URL.Hostname()); !ok {
+			return false
+		}
+	}
+	if r.path != "" {
+		if ok, _ := path.Match(r.path, httpRequest.URL.Path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type faultTransport struct {
+	injector *FaultInjector
+	next     http.RoundTripper
+}
+
+func (t *faultTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	fault := t.injector.fault(httpRequest)
+	if fault == nil {
+		return t.next.RoundTrip(httpRequest)
+	}
+	return fault(t.next, httpRequest)
+}
+
+func (t *faultTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
@@ -0,0 +1,259 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newFaultTestServer(hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set(contentTypeHeader, contentTypeJson)
+		_, _ = w.Write([]byte(`{"hello":"world"}`))
+	}))
+}
+
+func TestFaults(t *testing.T) {
+	var hits atomic.Int32
+	server := newFaultTestServer(&hits)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		fault    Fault
+		timeout  time.Duration
+		wantHits int32
+		check    func(t *testing.T, resp *Response, err error)
+	}{
+		{
+			name:     "latency",
+			fault:    LatencyFault(50 * time.Millisecond),
+			wantHits: 1,
+			check: func(t *testing.T, resp *Response, err error) {
+				if err != nil || resp.Timings.Total < 50*time.Millisecond {
+					t.Errorf("Do() = %v, %v", resp, err)
+				}
+			},
+		},
+		{
+			name:     "latency canceled",
+			fault:    LatencyFault(time.Minute),
+			timeout:  10 * time.Millisecond,
+			wantHits: 0,
+			check: func(t *testing.T, resp *Response, err error) {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Do() error = %v, want %v", err, context.DeadlineExceeded)
+				}
+			},
+		},
+		{
+			name:     "connection reset",
+			fault:    ConnectionResetFault(),
+			wantHits: 0,
+			check: func(t *testing.T, resp *Response, err error) {
+				if !errors.Is(err, syscall.ECONNRESET) {
+					t.Errorf("Do() error = %v, want %v", err, syscall.ECONNRESET)
+				}
+			},
+		},
+		{
+			name:     "timeout",
+			fault:    TimeoutFault(10 * time.Millisecond),
+			wantHits: 0,
+			check: func(t *testing.T, resp *Response, err error) {
+				var netErr net.Error
+				if !errors.As(err, &netErr) || !netErr.Timeout() {
+					t.Errorf("Do() error = %v, want timeout", err)
+				}
+			},
+		},
+		{
+			name:     "truncated body",
+			fault:    TruncatedBodyFault(5),
+			wantHits: 1,
+			check: func(t *testing.T, resp *Response, err error) {
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("Do() error = %v, want %v", err, io.ErrUnexpectedEOF)
+				}
+			},
+		},
+		{
+			name:     "truncated body of its size",
+			fault:    TruncatedBodyFault(len(`{"hello":"world"}`)),
+			wantHits: 1,
+			check: func(t *testing.T, resp *Response, err error) {
+				if err != nil || string(resp.RawBody) != `{"hello":"world"}` {
+					t.Errorf("Do() = %v, %v", resp, err)
+				}
+			},
+		},
+		{
+			name:     "truncated body shorter than size",
+			fault:    TruncatedBodyFault(100),
+			wantHits: 1,
+			check: func(t *testing.T, resp *Response, err error) {
+				if err != nil || string(resp.RawBody) != `{"hello":"world"}` {
+					t.Errorf("Do() = %v, %v", resp, err)
+				}
+			},
+		},
+		{
+			name:     "status",
+			fault:    StatusFault(http.StatusServiceUnavailable),
+			wantHits: 0,
+			check: func(t *testing.T, resp *Response, err error) {
+				if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+					t.Errorf("Do() = %v, %v", resp, err)
+				}
+			},
+		},
+		{
+			name:     "malformed json",
+			fault:    MalformedJSONFault(),
+			wantHits: 1,
+			check: func(t *testing.T, resp *Response, err error) {
+				if err != nil || resp.StatusCode != http.StatusOK {
+					t.Fatalf("Do() = %v, %v", resp, err)
+				}
+				if json.Valid(resp.RawBody) || string(resp.RawBody) != `{"hello"` {
+					t.Errorf("Do() body = %s", resp.RawBody)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			injector, _ := NewFaultInjector(WithFaultRule(tt.fault))
+			client, _ := newTestClient(server, WithFaultInjector(injector))
+
+			ctx := context.Background()
+			if tt.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			req, _ := NewRequest(http.MethodGet, "/", WithContext(ctx))
+			resp, err := client.Do(req)
+			tt.check(t, resp, err)
+			if hits.Load() != tt.wantHits {
+				t.Errorf("hits = %v, want %v", hits.Load(), tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestFaultInjector(t *testing.T) {
+	var hits atomic.Int32
+	server := newFaultTestServer(&hits)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		options    []FaultRuleOption
+		paths      []string
+		wantFaults []bool
+		wantErr    bool
+	}{
+		{
+			name:       "fault scoped by path",
+			options:    []FaultRuleOption{WithFaultPath("/users/*")},
+			paths:      []string{"/users/1", "/orders/1", "/users/2"},
+			wantFaults: []bool{true, false, true},
+		},
+		{
+			name:       "fault scoped by host",
+			options:    []FaultRuleOption{WithFaultHost("*.example.com")},
+			paths:      []string{"/users/1"},
+			wantFaults: []bool{false},
+		},
+		{
+			name:       "fault by schedule",
+			options:    []FaultRuleOption{WithFaultSchedule(false, true)},
+			paths:      []string{"/a", "/b", "/c", "/d"},
+			wantFaults: []bool{false, true, false, true},
+		},
+		{
+			name:       "fault never by probability",
+			options:    []FaultRuleOption{WithFaultProbability(0)},
+			paths:      []string{"/a", "/b"},
+			wantFaults: []bool{false, false},
+		},
+		{
+			name:    "fault with invalid probability",
+			options: []FaultRuleOption{WithFaultProbability(2)},
+			wantErr: true,
+		},
+		{
+			name:    "fault with invalid path pattern",
+			options: []FaultRuleOption{WithFaultPath("[")},
+			wantErr: true,
+		},
+		{
+			name:    "fault with empty schedule",
+			options: []FaultRuleOption{WithFaultSchedule()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector, err := NewFaultInjector(WithFaultRule(StatusFault(http.StatusTeapot), tt.options...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFaultInjector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			client, _ := newTestClient(server, WithFaultInjector(injector))
+
+			for i, path := range tt.paths {
+				req, _ := NewRequest(http.MethodGet, path)
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("Do() error = %v", err)
+				}
+				if got := resp.StatusCode == http.StatusTeapot; got != tt.wantFaults[i] {
+					t.Errorf("Do(%v) fault = %v, want %v", path, got, tt.wantFaults[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWithFaultSeed(t *testing.T) {
+	faults := func() []bool {
+		injector, _ := NewFaultInjector(
+			WithFaultSeed(42),
+			WithFaultRule(StatusFault(http.StatusTeapot), WithFaultProbability(0.5)),
+		)
+		var faults []bool
+		for i := 0; i < 20; i++ {
+			httpRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+			faults = append(faults, injector.fault(httpRequest) != nil)
+		}
+		return faults
+	}
+
+	first, second := faults(), faults()
+	injected := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("WithFaultSeed() faults = %v and %v, want equal", first, second)
+		}
+		if first[i] {
+			injected++
+		}
+	}
+	if injected == 0 || injected == len(first) {
+		t.Errorf("WithFaultSeed() injected %v of %v", injected, len(first))
+	}
+}
@@ -0,0 +1,466 @@
+package request
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR is an HTTP Archive 1.2 document, see
+// http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	// Error is set when no response was received.
+	Error string `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings are in milliseconds, -1 when they do not apply.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARRedactFunc changes an entry before it is recorded.
+type HARRedactFunc func(entry *HAREntry)
+
+type HARRecorderOption func(*HARRecorder) error
+
+// HARRecorder records every round trip of the clients using it, including
+// each redirect, as HAR entries.
+type HARRecorder struct {
+	maxBodySize int
+	redactors   []HARRedactFunc
+
+	mu      sync.Mutex
+	entries []*HAREntry
+}
+
+const (
+	harVersion            = "1.2"
+	harCreatorName        = "github.com/amuwall/go-request"
+	defaultHARMaxBodySize = 1 << 20
+)
+
+func NewHARRecorder(options ...HARRecorderOption) (recorder *HARRecorder, err error) {
+	recorder = &HARRecorder{
+		maxBodySize: defaultHARMaxBodySize,
+	}
+
+	for _, option := range options {
+		err = option(recorder)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// WithHARMaxBodySize caps the bytes of each recorded body, zero records no
+// body. The default is 1 MiB.
+func WithHARMaxBodySize(maxBodySize int) HARRecorderOption {
+	return func(r *HARRecorder) error {
+		if maxBodySize < 0 {
+			return fmt.Errorf("invalid har max body size %d", maxBodySize)
+		}
+		r.maxBodySize = maxBodySize
+		return nil
+	}
+}
+
+func WithHARRedactor(redactor HARRedactFunc) HARRecorderOption {
+	return func(r *HARRecorder) error {
+		r.redactors = append(r.redactors, redactor)
+		return nil
+	}
+}
+
+// WithHARRedactHeaders redacts the values of headers, and of cookies when
+// Cookie or Set-Cookie is given.
+func WithHARRedactHeaders(headers ...string) HARRecorderOption {
+	names := map[string]bool{}
+	for _, header := range headers {
+		names[http.CanonicalHeaderKey(header)] = true
+	}
+	return WithHARRedactor(func(entry *HAREntry) {
+		redactHARHeaders(entry.Request.Headers, names)
+		redactHARHeaders(entry.Response.Headers, names)
+		if names["Cookie"] {
+			redactHARCookies(entry.Request.Cookies)
+		}
+		if names["Set-Cookie"] {
+			redactHARCookies(entry.Response.Cookies)
+		}
+	})
+}
+
+func redactHARHeaders(headers []HARNameValue, names map[string]bool) {
+	for i := range headers {
+		if names[http.CanonicalHeaderKey(headers[i].Name)] {
+			headers[i].Value = redacted
+		}
+	}
+}
+
+func redactHARCookies(cookies []HARCookie) {
+	for i := range cookies {
+		cookies[i].Value = redacted
+	}
+}
+
+// WithHARRecorder records the traffic of the client in recorder.
+func WithHARRecorder(recorder *HARRecorder) ClientOption {
+	return func(c *Client) error {
+		if recorder == nil {
+			return fmt.Errorf("har recorder is nil")
+		}
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &harTransport{recorder: recorder, next: next}
+		})
+		return nil
+	}
+}
+
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &HAR{Log: HARLog{
+		Version: harVersion,
+		Creator: HARCreator{Name: harCreatorName, Version: harVersion},
+		Entries: append([]*HAREntry{}, r.entries...),
+	}}
+}
+
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = nil
+}
+
+func (r *HARRecorder) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.HAR())
+}
+
+// Save writes the HAR file atomically.
+func (r *HARRecorder) Save(filename string) error {
+	var buf bytes.Buffer
+	err := r.Write(&buf)
+	if err != nil {
+		return fmt.Errorf("marshal har error %w", err)
+	}
+
+	err = writeFileAtomic(filename, buf.Bytes(), 0o600)
+	if err != nil {
+		return fmt.Errorf("write har file error %w", err)
+	}
+	return nil
+}
+
+func (r *HARRecorder) record(entry *HAREntry) {
+	for _, redactor := range r.redactors {
+		redactor(entry)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+}
+
+type harTransport struct {
+	recorder *HARRecorder
+	next     http.RoundTripper
+}
+
+func (t *harTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	tracer := newTimingsTracer()
+	ctx := httptrace.WithClientTrace(httpRequest.Context(), tracer.clientTrace())
+	httpRequest = httpRequest.Clone(ctx)
+	requestBody, err := readRequestBody(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &HAREntry{
+		StartedDateTime: tracer.start.Format(time.RFC3339Nano),
+		Request:         t.recorder.harRequest(httpRequest, requestBody),
+	}
+
+	httpResponse, err := t.next.RoundTrip(httpRequest)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HARResponse{
+			Cookies: []HARCookie{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1,
+		}
+		t.finish(entry, tracer)
+		return nil, err
+	}
+
+	responseBody, readErr := io.ReadAll(httpResponse.Body)
+	_ = httpResponse.Body.Close()
+	httpResponse.Body = io.NopCloser(io.MultiReader(bytes.NewReader(responseBody), errorReader{readErr}))
+
+	entry.Response = t.recorder.harResponse(httpResponse, responseBody)
+	if readErr != nil {
+		entry.Error = readErr.Error()
+	}
+	t.finish(entry, tracer)
+
+	return httpResponse, nil
+}
+
+func (t *harTransport) finish(entry *HAREntry, tracer *timingsTracer) {
+	timings := tracer.finish()
+	optional := func(d time.Duration) float64 {
+		if d == 0 {
+			return -1
+		}
+		return milliseconds(d)
+	}
+
+	entry.Time = milliseconds(timings.Total)
+	entry.ServerIPAddress, entry.Connection = splitRemoteAddr(timings.RemoteAddr)
+	entry.Timings = HARTimings{
+		Blocked: -1,
+		DNS:     optional(timings.DNSLookup),
+		Connect: optional(timings.Connect + timings.TLSHandshake),
+		SSL:     optional(timings.TLSHandshake),
+		Send:    milliseconds(tracer.send()),
+		Wait:    milliseconds(timings.Wait),
+		Receive: milliseconds(timings.Transfer),
+	}
+
+	t.recorder.record(entry)
+}
+
+func (t *harTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func splitRemoteAddr(addr string) (ip, port string) {
+	for i := len(addr) - 1; i >= 0; i-- {
+		if addr[i] == ':' {
+			return trimBrackets(addr[:i]), addr[i+1:]
+		}
+	}
+	return addr, ""
+}
+
+func trimBrackets(host string) string {
+	if len(host) > 1 && host[0] == '[' && host[len(host)-1] == ']' {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+func harNameValues(values map[string][]string) []HARNameValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nameValues := []HARNameValue{}
+	for _, name := range names {
+		for _, value := range values[name] {
+			nameValues = append(nameValues, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return nameValues
+}
+
+func harCookies(cookies []*http.Cookie) []HARCookie {
+	harCookies := []HARCookie{}
+	for _, cookie := range cookies {
+		harCookie := HARCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Expires.IsZero() {
+			harCookie.Expires = cookie.Expires.Format(time.RFC3339)
+		}
+		harCookies = append(harCookies, harCookie)
+	}
+	return harCookies
+}
+
+// harText returns body as text, or base64 when it is binary, capped to the
+// max body size.
+func (r *HARRecorder) harText(body []byte) (text, encoding, comment string) {
+	if len(body) > r.maxBodySize {
+		comment = "truncated to " + strconv.Itoa(r.maxBodySize) + " bytes"
+		body = body[:r.maxBodySize]
+	}
+	if utf8.Valid(body) {
+		return string(body), "", comment
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64", comment
+}
+
+func (r *HARRecorder) harRequest(httpRequest *http.Request, body []byte) HARRequest {
+	header := httpRequest.Header.Clone()
+	if httpRequest.Host != "" && httpRequest.Host != httpRequest.URL.Host {
+		header.Set("Host", httpRequest.Host)
+	}
+
+	harRequest := HARRequest{
+		Method:      httpRequest.Method,
+		URL:         httpRequest.URL.String(),
+		HTTPVersion: httpRequest.Proto,
+		Cookies:     harCookies(httpRequest.Cookies()),
+		Headers:     harNameValues(header),
+		QueryString: harNameValues(httpRequest.URL.Query()),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if len(body) != 0 {
+		text, encoding, comment := r.harText(body)
+		if encoding != "" {
+			comment = "base64 encoded " + comment
+		}
+		harRequest.PostData = &HARPostData{
+			MimeType: httpRequest.Header.Get(contentTypeHeader),
+			Text:     text,
+			Comment:  comment,
+		}
+	}
+	return harRequest
+}
+
+func (r *HARRecorder) harResponse(httpResponse *http.Response, body []byte) HARResponse {
+	text, encoding, comment := r.harText(body)
+	redirectURL := ""
+	if location, err := httpResponse.Location(); err == nil {
+		redirectURL = location.String()
+	}
+
+	return HARResponse{
+		Status:      httpResponse.StatusCode,
+		StatusText:  http.StatusText(httpResponse.StatusCode),
+		HTTPVersion: httpResponse.Proto,
+		Cookies:     harCookies(httpResponse.Cookies()),
+		Headers:     harNameValues(httpResponse.Header),
+		Content: HARContent{
+			Size:     len(body),
+			MimeType: httpResponse.Header.Get(contentTypeHeader),
+			Text:     text,
+			Encoding: encoding,
+			Comment:  comment,
+		},
+		RedirectURL: redirectURL,
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
@@ -0,0 +1,251 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newHARTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/binary":
+			w.Header().Set(contentTypeHeader, "application/octet-stream")
+			_, _ = w.Write([]byte{0xff, 0xfe, 0x00})
+		default:
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+			w.Header().Set(contentTypeHeader, contentTypeJson)
+			_, _ = w.Write([]byte(`{"hello":"world"}`))
+		}
+	}))
+}
+
+func TestNewHARRecorder(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         []HARRecorderOption
+		wantMaxBodySize int
+		wantErr         bool
+	}{
+		{
+			name:            "new har recorder",
+			wantMaxBodySize: defaultHARMaxBodySize,
+		},
+		{
+			name:            "new har recorder with max body size",
+			options:         []HARRecorderOption{WithHARMaxBodySize(10)},
+			wantMaxBodySize: 10,
+		},
+		{
+			name:    "new har recorder with invalid max body size",
+			options: []HARRecorderOption{WithHARMaxBodySize(-1)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, err := NewHARRecorder(tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewHARRecorder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if recorder.maxBodySize != tt.wantMaxBodySize {
+				t.Errorf("NewHARRecorder() maxBodySize = %v, want %v", recorder.maxBodySize, tt.wantMaxBodySize)
+			}
+		})
+	}
+}
+
+func TestWithHARRecorder(t *testing.T) {
+	server := newHARTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		options []HARRecorderOption
+		request func() (*Request, error)
+		check   func(t *testing.T, entries []*HAREntry)
+	}{
+		{
+			name: "record redirects",
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/old", WithQueryParams(NewQueryParams(map[string]string{"q": "1"})))
+			},
+			check: func(t *testing.T, entries []*HAREntry) {
+				if len(entries) != 2 {
+					t.Fatalf("entries = %v, want 2", len(entries))
+				}
+				if entries[0].Response.Status != http.StatusFound || !strings.HasSuffix(entries[0].Response.RedirectURL, "/new") {
+					t.Errorf("redirect response = %+v", entries[0].Response)
+				}
+				wantQuery := []HARNameValue{{Name: "q", Value: "1"}}
+				if !reflect.DeepEqual(entries[0].Request.QueryString, wantQuery) {
+					t.Errorf("query = %v, want %v", entries[0].Request.QueryString, wantQuery)
+				}
+				final := entries[1]
+				if !strings.HasSuffix(final.Request.URL, "/new") || final.Response.Status != http.StatusOK {
+					t.Errorf("final entry = %v %v", final.Request.URL, final.Response.Status)
+				}
+				if final.Response.Content.Text != `{"hello":"world"}` || final.Response.Content.Size != 17 {
+					t.Errorf("content = %+v", final.Response.Content)
+				}
+				if len(final.Response.Cookies) != 1 || final.Response.Cookies[0].Value != "secret" {
+					t.Errorf("cookies = %+v", final.Response.Cookies)
+				}
+				if final.ServerIPAddress != "127.0.0.1" || final.Timings.Wait < 0 || final.Timings.Receive < 0 {
+					t.Errorf("entry = %+v", final)
+				}
+			},
+		},
+		{
+			name: "record post data",
+			request: func() (*Request, error) {
+				return NewRequest(
+					http.MethodPost, "/",
+					WithHeaders(map[string]string{contentTypeHeader: contentTypeJson}),
+					WithBodyParams(NewJsonBodyParams(map[string]string{"name": "value"})),
+				)
+			},
+			check: func(t *testing.T, entries []*HAREntry) {
+				postData := entries[0].Request.PostData
+				if postData == nil || postData.Text != `{"name":"value"}` || postData.MimeType != contentTypeJsonWithUTF8 {
+					t.Errorf("postData = %+v", postData)
+				}
+			},
+		},
+		{
+			name:    "record truncated body",
+			options: []HARRecorderOption{WithHARMaxBodySize(5)},
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/")
+			},
+			check: func(t *testing.T, entries []*HAREntry) {
+				content := entries[0].Response.Content
+				if content.Text != `{"hel` || content.Size != 17 || content.Comment != "truncated to 5 bytes" {
+					t.Errorf("content = %+v", content)
+				}
+			},
+		},
+		{
+			name: "record binary body",
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/binary")
+			},
+			check: func(t *testing.T, entries []*HAREntry) {
+				content := entries[0].Response.Content
+				if content.Text != "//4A" || content.Encoding != "base64" {
+					t.Errorf("content = %+v", content)
+				}
+			},
+		},
+		{
+			name: "record redacted headers",
+			options: []HARRecorderOption{
+				WithHARRedactHeaders("x-token", "set-cookie"),
+				WithHARRedactor(func(entry *HAREntry) {
+					entry.Response.Content.Text = ""
+				}),
+			},
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/", WithHeaders(map[string]string{"X-Token": "secret"}))
+			},
+			check: func(t *testing.T, entries []*HAREntry) {
+				entry := entries[0]
+				for _, header := range append(entry.Request.Headers, entry.Response.Headers...) {
+					if strings.Contains(header.Value, "secret") {
+						t.Errorf("header %v not redacted", header)
+					}
+				}
+				if entry.Response.Cookies[0].Value != redacted || entry.Response.Content.Text != "" {
+					t.Errorf("response = %+v", entry.Response)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, err := NewHARRecorder(tt.options...)
+			if err != nil {
+				t.Fatalf("NewHARRecorder() error = %v", err)
+			}
+			client, err := newTestClient(server, WithHARRecorder(recorder))
+			if err != nil {
+				t.Fatalf("newTestClient() error = %v", err)
+			}
+			req, err := tt.request()
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if len(resp.RawBody) == 0 {
+				t.Errorf("Do() response body is empty")
+			}
+			tt.check(t, recorder.HAR().Log.Entries)
+		})
+	}
+}
+
+func TestHARRecorder_Save(t *testing.T) {
+	server := newHARTestServer()
+	defer server.Close()
+
+	recorder, _ := NewHARRecorder()
+	client, _ := newTestClient(server, WithHARRecorder(recorder))
+	req, _ := NewRequest(http.MethodGet, "/")
+	_, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "traffic.har")
+	err = recorder.Save(filename)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(filename)
+	var har map[string]map[string]interface{}
+	err = json.Unmarshal(data, &har)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if har["log"]["version"] != "1.2" || len(har["log"]["entries"].([]interface{})) != 1 {
+		t.Errorf("Save() = %s", data)
+	}
+
+	recorder.Reset()
+	if len(recorder.HAR().Log.Entries) != 0 {
+		t.Errorf("Reset() entries = %v", recorder.HAR().Log.Entries)
+	}
+}
+
+func TestWithHARRecorder_error(t *testing.T) {
+	server := newHARTestServer()
+	server.Close()
+
+	recorder, _ := NewHARRecorder()
+	client, _ := newTestClient(server, WithHARRecorder(recorder))
+	req, _ := NewRequest(http.MethodGet, "/")
+	_, err := client.Do(req)
+	if err == nil {
+		t.Fatalf("Do() error = nil, want error")
+	}
+
+	entries := recorder.HAR().Log.Entries
+	if len(entries) != 1 || entries[0].Error == "" || entries[0].Response.Status != 0 {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,269 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type HealthCheckOption func(*healthChecker) error
+
+type healthChecker struct {
+	probe    *Request
+	interval time.Duration
+	timeout  time.Duration
+
+	maxLatency         time.Duration
+	statusCodes        []int
+	healthyThreshold   int
+	unhealthyThreshold int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type endpointHealth struct {
+	unhealthy bool
+
+	consecutiveSuccesses int
+	consecutiveFailures  int
+
+	lastCheck   time.Time
+	lastLatency time.Duration
+	lastErr     error
+}
+
+type EndpointStatus struct {
+	Endpoint
+
+	Healthy  bool
+	Ejected  bool
+	InFlight int64
+
+	LastCheck   time.Time
+	LastLatency time.Duration
+	LastError   error
+}
+
+var ErrHealthCheckLatency = errors.New("health check latency over threshold")
+
+const defaultHealthCheckTimeout = 5 * time.Second
+
+// WithHealthCheck sends probe to every endpoint each interval, endpoints which
+// fail the probe are not picked until they pass it again. It is stopped by
+// Client.Close.
+func WithHealthCheck(probe *Request, interval time.Duration, options ...HealthCheckOption) ClientOption {
+	return func(c *Client) error {
+		if probe == nil {
+			return errors.New("health check probe is nil")
+		}
+		if interval <= 0 {
+			return fmt.Errorf("invalid health check interval %s", interval)
+		}
+
+		checker := &healthChecker{
+			probe:    probe,
+			interval: interval,
+			timeout:  defaultHealthCheckTimeout,
+
+			healthyThreshold:   1,
+			unhealthyThreshold: 1,
+		}
+		for _, option := range options {
+			err := option(checker)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.health = checker
+		return nil
+	}
+}
+
+func WithHealthCheckTimeout(timeout time.Duration) HealthCheckOption {
+	return func(h *healthChecker) error {
+		h.timeout = timeout
+		return nil
+	}
+}
+
+// WithHealthCheckMaxLatency marks a probe slower than maxLatency as failed.
+func WithHealthCheckMaxLatency(maxLatency time.Duration) HealthCheckOption {
+	return func(h *healthChecker) error {
+		h.maxLatency = maxLatency
+		return nil
+	}
+}
+
+// WithHealthCheckStatusCodes sets the healthy status codes, any 2xx by default.
+func WithHealthCheckStatusCodes(statusCodes ...int) HealthCheckOption {
+	return func(h *healthChecker) error {
+		h.statusCodes = statusCodes
+		return nil
+	}
+}
+
+// WithHealthCheckThresholds sets how many probes in a row must pass to mark an
+// endpoint up, and must fail to mark it down.
+func WithHealthCheckThresholds(healthy, unhealthy int) HealthCheckOption {
+	return func(h *healthChecker) error {
+		if healthy <= 0 || unhealthy <= 0 {
+			return fmt.Errorf("invalid health check thresholds %d, %d", healthy, unhealthy)
+		}
+		h.healthyThreshold = healthy
+		h.unhealthyThreshold = unhealthy
+		return nil
+	}
+}
+
+func (h *healthChecker) start(c *Client) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			h.checkAll(ctx, c)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stop cancels the probes in flight and waits for them to return.
+func (h *healthChecker) stop() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+}
+
+func (h *healthChecker) checkAll(ctx context.Context, c *Client) {
+	var wg sync.WaitGroup
+	for _, endpoint := range c.pool.list() {
+		wg.Add(1)
+		go func(endpoint Endpoint) {
+			defer wg.Done()
+			latency, err := h.check(ctx, c, endpoint)
+			if ctx.Err() != nil {
+				// stopped, the probe failing says nothing about the endpoint
+				return
+			}
+			c.pool.reportHealth(endpoint, latency, err, h.healthyThreshold, h.unhealthyThreshold)
+		}(endpoint)
+	}
+	wg.Wait()
+}
+
+func (h *healthChecker) check(ctx context.Context, c *Client, endpoint Endpoint) (latency time.Duration, err error) {
+	// build sets the Content-Type in the headers, the endpoints are checked
+	// concurrently
+	probe := *h.probe
+	probe.Headers = h.probe.Headers.Clone()
+	httpRequest, err := probe.build(c.BaseURL())
+	if err != nil {
+		return
+	}
+
+	// sent like requests to the endpoint, only the dial goes to its address
+	ctx = context.WithValue(ctx, endpointKey{}, endpoint.Address())
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := c.send(httpRequest.WithContext(ctx))
+	latency = time.Since(start)
+	if err != nil {
+		return
+	}
+
+	if !h.healthyStatus(resp.StatusCode) {
+		return latency, fmt.Errorf("health check status code %d", resp.StatusCode)
+	}
+	if h.maxLatency > 0 && latency > h.maxLatency {
+		return latency, ErrHealthCheckLatency
+	}
+
+	return
+}
+
+func (h *healthChecker) healthyStatus(statusCode int) bool {
+	if len(h.statusCodes) == 0 {
+		return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+	}
+	for _, code := range h.statusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *endpointPool) reportHealth(endpoint Endpoint, latency time.Duration, err error, healthyThreshold, unhealthyThreshold int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, state := range p.endpoints {
+		if state.Address() != endpoint.Address() {
+			continue
+		}
+
+		health := &state.health
+		health.lastCheck = p.now()
+		health.lastLatency = latency
+		health.lastErr = err
+
+		if err != nil {
+			health.consecutiveSuccesses = 0
+			health.consecutiveFailures++
+			if health.consecutiveFailures >= unhealthyThreshold {
+				health.unhealthy = true
+			}
+		} else {
+			health.consecutiveFailures = 0
+			health.consecutiveSuccesses++
+			if health.consecutiveSuccesses >= healthyThreshold {
+				health.unhealthy = false
+			}
+		}
+	}
+}
+
+// Endpoints returns the current state of every endpoint set by WithEndpoints.
+func (c *Client) Endpoints() []EndpointStatus {
+	if c.pool == nil {
+		return nil
+	}
+
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+
+	now := c.pool.now()
+	statuses := make([]EndpointStatus, 0, len(c.pool.endpoints))
+	for _, state := range c.pool.endpoints {
+		statuses = append(statuses, EndpointStatus{
+			Endpoint: state.Endpoint,
+
+			Healthy:  !state.health.unhealthy,
+			Ejected:  state.ejectedUntil.After(now),
+			InFlight: state.loadInFlight(),
+
+			LastCheck:   state.health.lastCheck,
+			LastLatency: state.health.lastLatency,
+			LastError:   state.health.lastErr,
+		})
+	}
+	return statuses
+}
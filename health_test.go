@@ -0,0 +1,265 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, condition func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWithHealthCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Server", "healthy")
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Server", "failing")
+	}))
+	defer failing.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.Header().Set("X-Server", "slow")
+	}))
+	defer slow.Close()
+
+	probe, _ := NewRequest(http.MethodGet, "/health")
+	client, err := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newTestEndpoint(healthy, 1), newTestEndpoint(failing, 1), newTestEndpoint(slow, 1)),
+		WithHealthCheck(
+			probe,
+			10*time.Millisecond,
+			WithHealthCheckMaxLatency(20*time.Millisecond),
+			WithHealthCheckTimeout(time.Second),
+		),
+	)
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+	defer client.Close()
+
+	ok := waitFor(t, func() bool {
+		statuses := client.Endpoints()
+		return statuses[0].Healthy && !statuses[1].Healthy && !statuses[2].Healthy &&
+			!statuses[0].LastCheck.IsZero()
+	})
+	if !ok {
+		t.Errorf("Endpoints() = %+v, want only first endpoint healthy", client.Endpoints())
+		return
+	}
+	if err := client.Endpoints()[2].LastError; err != ErrHealthCheckLatency {
+		t.Errorf("Endpoints() LastError = %v, want %v", err, ErrHealthCheckLatency)
+	}
+
+	for i := 0; i < 3; i++ {
+		req, _ := NewRequest(http.MethodGet, "/api/test")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Do() error = %v", err)
+			return
+		}
+		if got := resp.Header.Get("X-Server"); got != "healthy" {
+			t.Errorf("Do() server = %v, want %v", got, "healthy")
+		}
+	}
+}
+
+func TestWithHealthCheck_thresholds(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	probe, _ := NewRequest(http.MethodGet, "/health")
+	client, _ := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newTestEndpoint(server, 1)),
+		WithHealthCheck(probe, 5*time.Millisecond, WithHealthCheckThresholds(2, 3)),
+	)
+	defer client.Close()
+
+	failing.Store(true)
+	if !waitFor(t, func() bool { return !client.Endpoints()[0].Healthy }) {
+		t.Errorf("Endpoints() Healthy = true, want false")
+		return
+	}
+
+	failing.Store(false)
+	if !waitFor(t, func() bool { return client.Endpoints()[0].Healthy }) {
+		t.Errorf("Endpoints() Healthy = false, want true")
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// counted by the client, a probe canceled by Close may still reach the
+	// server
+	var probes int32
+	probe, _ := NewRequest(http.MethodGet, "/health")
+	client, _ := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newTestEndpoint(server, 1)),
+		WithHealthCheck(probe, 5*time.Millisecond),
+		WithTimingsHook(func(*http.Request, Timings, error) {
+			atomic.AddInt32(&probes, 1)
+		}),
+	)
+	waitFor(t, func() bool { return atomic.LoadInt32(&probes) > 0 })
+
+	_ = client.Close()
+	closed := atomic.LoadInt32(&probes)
+	time.Sleep(30 * time.Millisecond)
+	if got := atomic.LoadInt32(&probes); got != closed {
+		t.Errorf("Close() probes = %v after close, want %v", got, closed)
+	}
+}
+
+func TestWithHealthCheck_invalid(t *testing.T) {
+	probe, _ := NewRequest(http.MethodGet, "/health")
+	tests := []struct {
+		name    string
+		options []ClientOption
+	}{
+		{name: "nil probe", options: []ClientOption{WithHealthCheck(nil, time.Second)}},
+		{name: "invalid interval", options: []ClientOption{WithHealthCheck(probe, 0)}},
+		{
+			name:    "invalid thresholds",
+			options: []ClientOption{WithHealthCheck(probe, time.Second, WithHealthCheckThresholds(0, 1))},
+		},
+		{name: "without endpoints", options: []ClientOption{WithHealthCheck(probe, time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient("127.0.0.1", tt.options...)
+			if err == nil {
+				t.Errorf("NewClient() error = %v, wantErr true", err)
+			}
+		})
+	}
+}
+
+func TestWithHealthCheck_host(t *testing.T) {
+	var mu sync.Mutex
+	hosts := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hosts[r.URL.Path] = r.Host
+	}))
+	defer server.Close()
+
+	probe, _ := NewRequest(http.MethodGet, "/health")
+	client, _ := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newTestEndpoint(server, 1)),
+		WithHealthCheck(probe, 5*time.Millisecond),
+	)
+	defer client.Close()
+
+	req, _ := NewRequest(http.MethodGet, "/api/test")
+	_, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return hosts["/health"] != ""
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hosts["/health"] != hosts["/api/test"] {
+		t.Errorf("probe Host = %v, want %v", hosts["/health"], hosts["/api/test"])
+	}
+}
+
+func TestWithHealthCheck_body(t *testing.T) {
+	var probes int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") == "application/json; charset=UTF-8" {
+			atomic.AddInt32(&probes, 1)
+		}
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	probe, _ := NewRequest(
+		http.MethodPost, "/health",
+		WithHeaders(map[string]string{"Content-Type": "application/json"}),
+		WithBodyParams(NewJsonBodyParams(map[string]string{"check": "deep"})),
+	)
+	client, _ := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newTestEndpoint(first, 1), newTestEndpoint(second, 1)),
+		WithHealthCheck(probe, 5*time.Millisecond),
+	)
+	defer client.Close()
+
+	if !waitFor(t, func() bool { return atomic.LoadInt32(&probes) >= 4 }) {
+		t.Errorf("probes = %v, want at least 4", atomic.LoadInt32(&probes))
+	}
+}
+
+func TestClient_Close_probeInFlight(t *testing.T) {
+	var probes int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	probe, _ := NewRequest(http.MethodGet, "/health")
+	client, _ := NewClient(
+		"api.example.com",
+		WithScheme("http"),
+		WithEndpoints(newTestEndpoint(server, 1)),
+		WithHealthCheck(probe, time.Hour, WithHealthCheckTimeout(3*time.Second)),
+	)
+	waitFor(t, func() bool { return atomic.LoadInt32(&probes) > 0 })
+
+	start := time.Now()
+	_ = client.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() took %v, want the probe canceled", elapsed)
+	}
+	if status := client.Endpoints()[0]; !status.Healthy || status.LastError != nil {
+		t.Errorf("Endpoints() = %+v, want healthy without error", status)
+	}
+}
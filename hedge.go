@@ -0,0 +1,234 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type HedgeOption func(*hedger) error
+
+type hedger struct {
+	delay     time.Duration
+	maxHedges int
+
+	percentile float64
+	latencies  []time.Duration
+	next       int
+	filled     bool
+
+	budgetRatio float64
+	budgetBurst float64
+	tokens      float64
+
+	mu sync.Mutex
+}
+
+type hedgeResult struct {
+	resp *Response
+	err  error
+}
+
+const (
+	defaultMaxHedges        = 1
+	defaultHedgeWindow      = 100
+	minHedgeLatencySamples  = 10
+	defaultHedgeBudgetRatio = 0.1
+	defaultHedgeBudgetBurst = 10
+)
+
+// WithHedging sends a duplicate of a GET, HEAD or OPTIONS request when it has
+// not completed after delay, the first successful response is returned and the
+// other attempts are canceled.
+func WithHedging(delay time.Duration, options ...HedgeOption) ClientOption {
+	return func(c *Client) error {
+		if delay <= 0 {
+			return fmt.Errorf("invalid hedging delay %s", delay)
+		}
+
+		h := &hedger{
+			delay:     delay,
+			maxHedges: defaultMaxHedges,
+
+			budgetRatio: defaultHedgeBudgetRatio,
+			budgetBurst: defaultHedgeBudgetBurst,
+			tokens:      defaultHedgeBudgetBurst,
+		}
+		for _, option := range options {
+			err := option(h)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.hedger = h
+		return nil
+	}
+}
+
+func WithMaxHedges(maxHedges int) HedgeOption {
+	return func(h *hedger) error {
+		if maxHedges <= 0 {
+			return fmt.Errorf("invalid max hedges %d", maxHedges)
+		}
+		h.maxHedges = maxHedges
+		return nil
+	}
+}
+
+// WithHedgePercentile learns the delay as the percentile, between 0 and 1, of
+// the latency of the last window requests. The fixed delay is used until
+// enough requests are seen.
+func WithHedgePercentile(percentile float64, window int) HedgeOption {
+	return func(h *hedger) error {
+		if percentile <= 0 || percentile >= 1 {
+			return fmt.Errorf("invalid hedging percentile %v", percentile)
+		}
+		if window < minHedgeLatencySamples {
+			window = defaultHedgeWindow
+		}
+		h.percentile = percentile
+		h.latencies = make([]time.Duration, window)
+		return nil
+	}
+}
+
+// WithHedgeBudget limits hedges to ratio of the requests, with burst hedges
+// allowed at once. The default is 10% with a burst of 10.
+func WithHedgeBudget(ratio float64, burst int) HedgeOption {
+	return func(h *hedger) error {
+		if ratio < 0 || burst < 1 {
+			return fmt.Errorf("invalid hedging budget %v, %d", ratio, burst)
+		}
+		h.budgetRatio = ratio
+		h.budgetBurst = float64(burst)
+		h.tokens = float64(burst)
+		return nil
+	}
+}
+
+func (h *hedger) hedgeable(httpRequest *http.Request) bool {
+	switch httpRequest.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return httpRequest.Body == nil || httpRequest.Body == http.NoBody || httpRequest.GetBody != nil
+	}
+	return false
+}
+
+func (h *hedger) do(httpRequest *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	ctx, cancel := context.WithCancel(httpRequest.Context())
+	defer cancel()
+
+	h.deposit()
+
+	results := make(chan hedgeResult, h.maxHedges+1)
+	launch := func(attempt int) error {
+		attemptRequest := httpRequest.Clone(ctx)
+		if attempt > 0 && httpRequest.GetBody != nil {
+			body, err := httpRequest.GetBody()
+			if err != nil {
+				return err
+			}
+			attemptRequest.Body = body
+		}
+		go func() {
+			resp, err := send(attemptRequest)
+			results <- hedgeResult{resp: resp, err: err}
+		}()
+		return nil
+	}
+
+	start := time.Now()
+	_ = launch(0)
+	pending, hedges := 1, 0
+
+	timer := time.NewTimer(h.hedgeDelay())
+	defer timer.Stop()
+
+	for {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil && result.resp.StatusCode < http.StatusInternalServerError {
+				h.observe(time.Since(start))
+				return result.resp, nil
+			}
+			if pending == 0 {
+				return result.resp, result.err
+			}
+		case <-timer.C:
+			if hedges >= h.maxHedges || !h.withdraw() {
+				continue
+			}
+			if err := launch(hedges + 1); err != nil {
+				continue
+			}
+			hedges++
+			pending++
+			timer.Reset(h.hedgeDelay())
+		}
+	}
+}
+
+func (h *hedger) hedgeDelay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.latencies == nil {
+		return h.delay
+	}
+
+	count := h.next
+	if h.filled {
+		count = len(h.latencies)
+	}
+	if count < minHedgeLatencySamples {
+		return h.delay
+	}
+
+	sorted := make([]time.Duration, count)
+	copy(sorted, h.latencies[:count])
+	sort.Slice(sorted, func(i, k int) bool {
+		return sorted[i] < sorted[k]
+	})
+	return sorted[int(h.percentile*float64(count-1))]
+}
+
+func (h *hedger) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.latencies == nil {
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next++
+	if h.next == len(h.latencies) {
+		h.next = 0
+		h.filled = true
+	}
+}
+
+func (h *hedger) deposit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tokens += h.budgetRatio
+	if h.tokens > h.budgetBurst {
+		h.tokens = h.budgetBurst
+	}
+}
+
+func (h *hedger) withdraw() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
@@ -0,0 +1,180 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSlowFirstServer(slow time.Duration) (*httptest.Server, *int32, *int32) {
+	var requests, canceled int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-time.After(slow):
+			case <-r.Context().Done():
+				atomic.AddInt32(&canceled, 1)
+				return
+			}
+		}
+		w.Header().Set("X-Request", "done")
+	}))
+	return server, &requests, &canceled
+}
+
+func TestWithHedging(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		options      []HedgeOption
+		wantRequests int32
+		wantFast     bool
+	}{
+		{
+			name:         "hedge slow get",
+			method:       http.MethodGet,
+			wantRequests: 2,
+			wantFast:     true,
+		},
+		{
+			name:         "do not hedge post",
+			method:       http.MethodPost,
+			wantRequests: 1,
+			wantFast:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests, canceled := newSlowFirstServer(300 * time.Millisecond)
+			defer server.Close()
+
+			client, err := newTestClient(server, WithHedging(20*time.Millisecond, tt.options...))
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+
+			req, _ := NewRequest(tt.method, "/api/test")
+			start := time.Now()
+			resp, err := client.Do(req)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if resp.Header.Get("X-Request") != "done" {
+				t.Errorf("Do() response = %v, want done", resp.Header)
+			}
+			if gotFast := elapsed < 200*time.Millisecond; gotFast != tt.wantFast {
+				t.Errorf("Do() elapsed = %v, wantFast %v", elapsed, tt.wantFast)
+			}
+			if got := atomic.LoadInt32(requests); got != tt.wantRequests {
+				t.Errorf("Do() requests = %v, want %v", got, tt.wantRequests)
+			}
+			if tt.wantRequests > 1 && !waitFor(t, func() bool { return atomic.LoadInt32(canceled) == 1 }) {
+				t.Errorf("Do() slow attempt not canceled")
+			}
+		})
+	}
+}
+
+func TestWithHedging_budget(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server, WithHedging(10*time.Millisecond, WithHedgeBudget(0, 1)))
+	for i := 0; i < 3; i++ {
+		req, _ := NewRequest(http.MethodGet, "/api/test")
+		if _, err := client.Do(req); err != nil {
+			t.Errorf("Do() error = %v", err)
+			return
+		}
+	}
+
+	// only the burst of one hedge is allowed without budget ratio
+	if got := atomic.LoadInt32(&requests); got != 4 {
+		t.Errorf("Do() requests = %v, want %v", got, 4)
+	}
+}
+
+func TestWithHedging_context(t *testing.T) {
+	server, _, _ := newSlowFirstServer(time.Second)
+	defer server.Close()
+
+	client, _ := newTestClient(server, WithHedging(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req, _ := NewRequest(http.MethodGet, "/api/test", WithContext(ctx))
+	_, err := client.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func Test_hedger_hedgeDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies int
+		want      time.Duration
+	}{
+		{
+			name:      "fixed delay without enough samples",
+			latencies: 5,
+			want:      time.Second,
+		},
+		{
+			name:      "percentile delay",
+			latencies: 100,
+			want:      90 * time.Millisecond,
+		},
+		{
+			name:      "percentile delay of last window",
+			latencies: 150,
+			want:      140 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient("127.0.0.1", WithHedging(time.Second, WithHedgePercentile(0.9, 100)))
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+			for i := 1; i <= tt.latencies; i++ {
+				client.hedger.observe(time.Duration(i) * time.Millisecond)
+			}
+			if got := client.hedger.hedgeDelay(); got != tt.want {
+				t.Errorf("hedgeDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithHedging_invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		option ClientOption
+	}{
+		{name: "invalid delay", option: WithHedging(0)},
+		{name: "invalid max hedges", option: WithHedging(time.Second, WithMaxHedges(0))},
+		{name: "invalid percentile", option: WithHedging(time.Second, WithHedgePercentile(1, 100))},
+		{name: "invalid budget", option: WithHedging(time.Second, WithHedgeBudget(-1, 1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient("127.0.0.1", tt.option)
+			if err == nil {
+				t.Errorf("NewClient() error = %v, wantErr true", err)
+			}
+		})
+	}
+}
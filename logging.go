@@ -0,0 +1,292 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type LogOption func(*requestLogger) error
+
+type requestLogger struct {
+	logger *slog.Logger
+
+	startLevel   slog.Level
+	finishLevel  slog.Level
+	failureLevel slog.Level
+
+	logHeaders  bool
+	logBodies   bool
+	maxBodySize int
+
+	redactHeaders map[string]bool
+	redactQuery   map[string]bool
+	redactJSON    [][]string
+}
+
+const (
+	redacted               = "[REDACTED]"
+	defaultLogMaxBodySize  = 4096
+	redactedQueryValue     = "REDACTED"
+	jsonRedactPathWildcard = "*"
+)
+
+var defaultRedactHeaders = []string{authorizationHeader, "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// WithLogger logs the start and the finish of every attempt of a request,
+// failures are transport errors and 5xx responses. Authorization and cookie
+// headers are always redacted.
+func WithLogger(logger *slog.Logger, options ...LogOption) ClientOption {
+	return func(c *Client) error {
+		if logger == nil {
+			return fmt.Errorf("logger is nil")
+		}
+
+		l := &requestLogger{
+			logger: logger,
+
+			startLevel:   slog.LevelDebug,
+			finishLevel:  slog.LevelInfo,
+			failureLevel: slog.LevelError,
+
+			maxBodySize: defaultLogMaxBodySize,
+
+			redactHeaders: map[string]bool{},
+			redactQuery:   map[string]bool{},
+		}
+		for _, header := range defaultRedactHeaders {
+			l.redactHeaders[header] = true
+		}
+		for _, option := range options {
+			err := option(l)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.logger = l
+		return nil
+	}
+}
+
+func WithLogLevels(start, finish, failure slog.Level) LogOption {
+	return func(l *requestLogger) error {
+		l.startLevel, l.finishLevel, l.failureLevel = start, finish, failure
+		return nil
+	}
+}
+
+func WithLogHeaders() LogOption {
+	return func(l *requestLogger) error {
+		l.logHeaders = true
+		return nil
+	}
+}
+
+// WithLogBodies logs request and response bodies truncated to maxSize bytes,
+// a maxSize of zero uses 4096.
+func WithLogBodies(maxSize int) LogOption {
+	return func(l *requestLogger) error {
+		if maxSize < 0 {
+			return fmt.Errorf("invalid log body size %d", maxSize)
+		}
+		if maxSize == 0 {
+			maxSize = defaultLogMaxBodySize
+		}
+		l.logBodies = true
+		l.maxBodySize = maxSize
+		return nil
+	}
+}
+
+func WithLogRedactHeaders(headers ...string) LogOption {
+	return func(l *requestLogger) error {
+		for _, header := range headers {
+			l.redactHeaders[http.CanonicalHeaderKey(header)] = true
+		}
+		return nil
+	}
+}
+
+func WithLogRedactQuery(keys ...string) LogOption {
+	return func(l *requestLogger) error {
+		for _, key := range keys {
+			l.redactQuery[key] = true
+		}
+		return nil
+	}
+}
+
+// WithLogRedactJSON redacts fields of JSON bodies by dot separated paths, like
+// user.password, where * matches any key or array element.
+func WithLogRedactJSON(paths ...string) LogOption {
+	return func(l *requestLogger) error {
+		for _, path := range paths {
+			if path == "" {
+				return fmt.Errorf("empty json redact path")
+			}
+			l.redactJSON = append(l.redactJSON, strings.Split(path, "."))
+		}
+		return nil
+	}
+}
+
+type attemptCounterKey struct{}
+
+func withAttemptCounter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptCounterKey{}, new(atomic.Int32))
+}
+
+func nextAttempt(ctx context.Context) int {
+	counter, ok := ctx.Value(attemptCounterKey{}).(*atomic.Int32)
+	if !ok {
+		return 1
+	}
+	return int(counter.Add(1))
+}
+
+func (l *requestLogger) do(httpRequest *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	ctx := httpRequest.Context()
+	attrs := []slog.Attr{
+		slog.String("method", httpRequest.Method),
+		slog.String("url", l.redactURL(httpRequest.URL)),
+		slog.Int("attempt", nextAttempt(ctx)),
+	}
+
+	if l.logger.Enabled(ctx, l.startLevel) {
+		startAttrs := append([]slog.Attr{}, attrs...)
+		if l.logHeaders {
+			startAttrs = append(startAttrs, slog.Any("request_headers", l.redactHeader(httpRequest.Header)))
+		}
+		if l.logBodies {
+			body, err := readRequestBody(httpRequest)
+			if err == nil && len(body) != 0 {
+				startAttrs = append(startAttrs, slog.String("request_body", l.formatBody(body)))
+			}
+		}
+		l.logger.LogAttrs(ctx, l.startLevel, "request started", startAttrs...)
+	}
+
+	start := time.Now()
+	resp, err := send(httpRequest)
+	attrs = append(attrs,
+		slog.Duration("duration", time.Since(start)),
+		slog.Int64("request_size", max(httpRequest.ContentLength, 0)),
+	)
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		l.logger.LogAttrs(ctx, l.failureLevel, "request failed", attrs...)
+		return resp, err
+	}
+
+	level := l.finishLevel
+	if resp.StatusCode >= http.StatusInternalServerError {
+		level = l.failureLevel
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return resp, err
+	}
+
+	attrs = append(attrs,
+		slog.Int("status", resp.StatusCode),
+		slog.Int("response_size", len(resp.RawBody)),
+	)
+	if l.logHeaders {
+		attrs = append(attrs, slog.Any("response_headers", l.redactHeader(resp.Header)))
+	}
+	if l.logBodies && len(resp.RawBody) != 0 {
+		attrs = append(attrs, slog.String("response_body", l.formatBody(resp.RawBody)))
+	}
+	l.logger.LogAttrs(ctx, level, "request finished", attrs...)
+
+	return resp, err
+}
+
+func (l *requestLogger) redactURL(u *url.URL) string {
+	redactedURL := *u
+	if len(l.redactQuery) != 0 && u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if l.redactQuery[key] {
+				query[key] = []string{redactedQueryValue}
+			}
+		}
+		redactedURL.RawQuery = query.Encode()
+	}
+	return redactedURL.Redacted()
+}
+
+func (l *requestLogger) redactHeader(header http.Header) map[string]string {
+	values := make(map[string]string, len(header))
+	for name := range header {
+		if l.redactHeaders[name] {
+			values[name] = redacted
+			continue
+		}
+		values[name] = strings.Join(header.Values(name), ", ")
+	}
+	return values
+}
+
+func (l *requestLogger) formatBody(body []byte) string {
+	body = l.redactBody(body)
+	if len(body) <= l.maxBodySize {
+		return string(body)
+	}
+	return fmt.Sprintf("%s...(%d bytes truncated)", body[:l.maxBodySize], len(body)-l.maxBodySize)
+}
+
+// redactBody redacts the configured paths of a JSON body, other bodies are
+// returned unchanged.
+func (l *requestLogger) redactBody(body []byte) []byte {
+	if len(l.redactJSON) == 0 {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if decoder.Decode(&value) != nil {
+		return body
+	}
+
+	for _, path := range l.redactJSON {
+		value = redactJSONPath(value, path)
+	}
+
+	redactedBody, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return redactedBody
+}
+
+func redactJSONPath(value any, path []string) any {
+	if len(path) == 0 {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if path[0] == jsonRedactPathWildcard || path[0] == key {
+				v[key] = redactJSONPath(field, path[1:])
+			}
+		}
+	case []any:
+		for i, element := range v {
+			if path[0] == jsonRedactPathWildcard || path[0] == fmt.Sprint(i) {
+				v[i] = redactJSONPath(element, path[1:])
+			}
+		}
+	}
+	return value
+}
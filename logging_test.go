@@ -0,0 +1,224 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), &buf
+}
+
+func parseLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWithLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"secret","data":"0123456789"}`))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger()
+	client, err := newTestClient(server, WithLogger(
+		logger,
+		WithLogHeaders(),
+		WithLogBodies(40),
+		WithLogRedactHeaders("x-api-key"),
+		WithLogRedactQuery("token"),
+		WithLogRedactJSON("password", "token"),
+	))
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+
+	req, _ := NewRequest(
+		http.MethodPost, "/api/login",
+		WithQueryParams(NewQueryParams(map[string]string{"token": "secret", "page": "1"})),
+		WithHeaders(map[string]string{"Authorization": "Bearer secret", "X-Api-Key": "secret", "X-Trace": "1"}),
+		WithBodyParams(NewJsonBodyParams(map[string]string{"user": "amu", "password": "secret"})),
+	)
+	if _, err := client.Do(req); err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("WithLogger() logs secret: %s", buf.String())
+	}
+
+	records := parseLogRecords(t, buf)
+	if len(records) != 2 {
+		t.Errorf("WithLogger() records = %v, want 2", len(records))
+		return
+	}
+	start, finish := records[0], records[1]
+
+	wantURL := server.URL + "/api/login?page=1&token=REDACTED"
+	tests := []struct {
+		name   string
+		record map[string]any
+		key    string
+		want   any
+	}{
+		{name: "start message", record: start, key: "msg", want: "request started"},
+		{name: "start level", record: start, key: "level", want: "DEBUG"},
+		{name: "start url", record: start, key: "url", want: wantURL},
+		{name: "start attempt", record: start, key: "attempt", want: float64(1)},
+		{
+			name:   "request headers",
+			record: start,
+			key:    "request_headers",
+			want: map[string]any{
+				"Authorization": "[REDACTED]",
+				"X-Api-Key":     "[REDACTED]",
+				"X-Trace":       "1",
+			},
+		},
+		{name: "request body", record: start, key: "request_body", want: `{"password":"[REDACTED]","user":"amu"}`},
+		{name: "finish message", record: finish, key: "msg", want: "request finished"},
+		{name: "finish level", record: finish, key: "level", want: "INFO"},
+		{name: "finish status", record: finish, key: "status", want: float64(http.StatusOK)},
+		{name: "request size", record: finish, key: "request_size", want: float64(34)},
+		{name: "response size", record: finish, key: "response_size", want: float64(38)},
+		{
+			name:   "response body",
+			record: finish,
+			key:    "response_body",
+			want:   `{"data":"0123456789","token":"[REDACTED]...(2 bytes truncated)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record[tt.key]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithLogger() %s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithLogger_failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		options     []ClientOption
+		wantMsg     []string
+		wantAttempt []float64
+	}{
+		{
+			name:        "server error",
+			wantMsg:     []string{"request finished"},
+			wantAttempt: []float64{1},
+		},
+		{
+			name: "connection error of every endpoint",
+			options: []ClientOption{
+				WithScheme("http"),
+				WithEndpoints(newClosedEndpoint(t), newClosedEndpoint(t)),
+			},
+			wantMsg:     []string{"request failed", "request failed"},
+			wantAttempt: []float64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, buf := newTestLogger()
+			levels := WithLogLevels(slog.LevelDebug-4, slog.LevelInfo, slog.LevelWarn)
+			client, err := newTestClient(server, append([]ClientOption{WithLogger(logger, levels)}, tt.options...)...)
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+
+			req, _ := NewRequest(http.MethodGet, "/api/test")
+			_, _ = client.Do(req)
+
+			var gotMsg []string
+			var gotAttempt []float64
+			for _, record := range parseLogRecords(t, buf) {
+				if record["level"] != "WARN" {
+					t.Errorf("WithLogger() level = %v, want WARN", record["level"])
+				}
+				gotMsg = append(gotMsg, record["msg"].(string))
+				gotAttempt = append(gotAttempt, record["attempt"].(float64))
+			}
+			if !reflect.DeepEqual(gotMsg, tt.wantMsg) || !reflect.DeepEqual(gotAttempt, tt.wantAttempt) {
+				t.Errorf("WithLogger() msg = %v attempt = %v, want %v %v", gotMsg, gotAttempt, tt.wantMsg, tt.wantAttempt)
+			}
+		})
+	}
+}
+
+func Test_redactJSONPath(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		path string
+		want string
+	}{
+		{
+			name: "nested field",
+			body: `{"user":{"name":"amu","password":"secret"}}`,
+			path: "user.password",
+			want: `{"user":{"name":"amu","password":"[REDACTED]"}}`,
+		},
+		{
+			name: "wildcard array elements",
+			body: `{"cards":[{"number":"1"},{"number":"2"}]}`,
+			path: "cards.*.number",
+			want: `{"cards":[{"number":"[REDACTED]"},{"number":"[REDACTED]"}]}`,
+		},
+		{
+			name: "array index",
+			body: `[{"id":1},{"id":2}]`,
+			path: "1.id",
+			want: `[{"id":1},{"id":"[REDACTED]"}]`,
+		},
+		{
+			name: "missing field",
+			body: `{"user":"amu"}`,
+			path: "user.password",
+			want: `{"user":"amu"}`,
+		},
+		{
+			name: "not json",
+			body: `user=amu`,
+			path: "user",
+			want: `user=amu`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &requestLogger{redactJSON: [][]string{strings.Split(tt.path, ".")}}
+			if got := string(l.redactBody([]byte(tt.body))); got != tt.want {
+				t.Errorf("redactBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package request
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MetricsOption func(*Metrics) error
+
+// Metrics collects request counts, latencies, in-flight requests and body
+// sizes of the clients using it, and serves them in the Prometheus text
+// exposition format.
+type Metrics struct {
+	namespace   string
+	buckets     []float64
+	sizeBuckets []float64
+
+	mu            sync.Mutex
+	requests      map[metricLabels]uint64
+	durations     map[metricLabels]*histogram
+	requestSizes  map[metricLabels]*histogram
+	responseSizes map[metricLabels]*histogram
+	inFlight      map[metricLabels]int64
+}
+
+type metricLabels struct {
+	host   string
+	method string
+	path   string
+	status string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+const defaultMetricsNamespace = "http_client"
+
+var (
+	defaultMetricsBuckets     = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	defaultMetricsSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+func NewMetrics(options ...MetricsOption) (metrics *Metrics, err error) {
+	metrics = &Metrics{
+		namespace:   defaultMetricsNamespace,
+		buckets:     defaultMetricsBuckets,
+		sizeBuckets: defaultMetricsSizeBuckets,
+
+		requests:      map[metricLabels]uint64{},
+		durations:     map[metricLabels]*histogram{},
+		requestSizes:  map[metricLabels]*histogram{},
+		responseSizes: map[metricLabels]*histogram{},
+		inFlight:      map[metricLabels]int64{},
+	}
+
+	for _, option := range options {
+		err = option(metrics)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(m *Metrics) error {
+		if namespace == "" {
+			return fmt.Errorf("empty metrics namespace")
+		}
+		m.namespace = namespace
+		return nil
+	}
+}
+
+// WithMetricsBuckets sets the upper bounds, in seconds, of the latency
+// histogram buckets.
+func WithMetricsBuckets(buckets ...float64) MetricsOption {
+	return func(m *Metrics) (err error) {
+		m.buckets, err = sortedBuckets(buckets)
+		return
+	}
+}
+
+// WithMetricsSizeBuckets sets the upper bounds, in bytes, of the body size
+// histogram buckets.
+func WithMetricsSizeBuckets(buckets ...float64) MetricsOption {
+	return func(m *Metrics) (err error) {
+		m.sizeBuckets, err = sortedBuckets(buckets)
+		return
+	}
+}
+
+func sortedBuckets(buckets []float64) ([]float64, error) {
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("empty metrics buckets")
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return nil, fmt.Errorf("duplicate metrics bucket %v", sorted[i])
+		}
+	}
+	return sorted, nil
+}
+
+// WithMetrics records the requests of the client in metrics, which can be
+// shared by several clients. Requests are labeled by the path template set
+// with WithPathTemplate, or else by "unknown" to keep the number of series
+// bounded.
+func WithMetrics(metrics *Metrics) ClientOption {
+	return func(c *Client) error {
+		if metrics == nil {
+			return fmt.Errorf("metrics is nil")
+		}
+		c.metrics = metrics
+		return nil
+	}
+}
+
+const unknownMetricPath = "unknown"
+
+func newMetricLabels(req *Request, httpRequest *http.Request) metricLabels {
+	host := httpRequest.Host
+	if host == "" {
+		host = httpRequest.URL.Host
+	}
+	path := req.PathTemplate
+	if path == "" {
+		path = unknownMetricPath
+	}
+	return metricLabels{host: host, method: httpRequest.Method, path: path}
+}
+
+func statusClass(resp *Response, err error) string {
+	if err != nil || resp == nil {
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode/100) + "xx"
+}
+
+// begin counts a request in flight until end records it.
+func (m *Metrics) begin(labels metricLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[labels]++
+}
+
+func (m *Metrics) end(labels metricLabels, httpRequest *http.Request, resp *Response, err error, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[labels]--
+
+	labels.status = statusClass(resp, err)
+	m.requests[labels]++
+	m.observe(m.durations, m.buckets, labels, elapsed.Seconds())
+	m.observe(m.requestSizes, m.sizeBuckets, labels, float64(max(httpRequest.ContentLength, 0)))
+	if resp != nil {
+		m.observe(m.responseSizes, m.sizeBuckets, labels, float64(len(resp.RawBody)))
+	}
+}
+
+func (m *Metrics) observe(histograms map[metricLabels]*histogram, buckets []float64, labels metricLabels, value float64) {
+	h, ok := histograms[labels]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		histograms[labels] = h
+	}
+	for i, bound := range buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (m *Metrics) do(req *Request, httpRequest *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	labels := newMetricLabels(req, httpRequest)
+	m.begin(labels)
+
+	start := time.Now()
+	resp, err := send(httpRequest)
+	m.end(labels, httpRequest, resp, err, time.Since(start))
+
+	return resp, err
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(contentTypeHeader, "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writer := bufio.NewWriter(w)
+
+	name := m.namespace + "_requests_total"
+	writeMetricHeader(writer, name, "counter", "Total number of requests by status class.")
+	for _, labels := range sortedLabels(m.requests) {
+		fmt.Fprintf(writer, "%s%s %d\n", name, labels.format(""), m.requests[labels])
+	}
+
+	name = m.namespace + "_requests_in_flight"
+	writeMetricHeader(writer, name, "gauge", "Number of requests in flight.")
+	for _, labels := range sortedLabels(m.inFlight) {
+		fmt.Fprintf(writer, "%s%s %d\n", name, labels.format(""), m.inFlight[labels])
+	}
+
+	writeHistograms(writer, m.namespace+"_request_duration_seconds", "Request latency in seconds.",
+		m.buckets, m.durations)
+	writeHistograms(writer, m.namespace+"_request_size_bytes", "Request body size in bytes.",
+		m.sizeBuckets, m.requestSizes)
+	writeHistograms(writer, m.namespace+"_response_size_bytes", "Response body size in bytes.",
+		m.sizeBuckets, m.responseSizes)
+
+	return writer.Flush()
+}
+
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeHistograms(w io.Writer, name, help string, buckets []float64, histograms map[metricLabels]*histogram) {
+	writeMetricHeader(w, name, "histogram", help)
+	for _, labels := range sortedLabels(histograms) {
+		h := histograms[labels]
+		var cumulative uint64
+		for i, bound := range buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels.format(formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels.format("+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, labels.format(""), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, labels.format(""), h.count)
+	}
+}
+
+func sortedLabels[V any](values map[metricLabels]V) []metricLabels {
+	labels := make([]metricLabels, 0, len(values))
+	for l := range values {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, k int) bool {
+		a, b := labels[i], labels[k]
+		if a.host != b.host {
+			return a.host < b.host
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.status < b.status
+	})
+	return labels
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (l metricLabels) format(le string) string {
+	var builder strings.Builder
+	builder.WriteString(`{host="`)
+	builder.WriteString(labelValueReplacer.Replace(l.host))
+	builder.WriteString(`",method="`)
+	builder.WriteString(labelValueReplacer.Replace(l.method))
+	builder.WriteString(`",path="`)
+	builder.WriteString(labelValueReplacer.Replace(l.path))
+	builder.WriteString(`"`)
+	if l.status != "" {
+		builder.WriteString(`,status="`)
+		builder.WriteString(l.status)
+		builder.WriteString(`"`)
+	}
+	if le != "" {
+		builder.WriteString(`,le="`)
+		builder.WriteString(le)
+		builder.WriteString(`"`)
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,138 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_WriteText(t *testing.T) {
+	metrics, err := NewMetrics(WithMetricsNamespace("test"), WithMetricsBuckets(1, 0.1), WithMetricsSizeBuckets(10))
+	if err != nil {
+		t.Errorf("NewMetrics() error = %v", err)
+		return
+	}
+
+	httpRequest := &http.Request{Method: http.MethodGet, Host: `api"example.com`, URL: &url.URL{}}
+	req := &Request{Path: "/api/users/1", PathTemplate: "/api/users/{id}"}
+	labels := newMetricLabels(req, httpRequest)
+	metrics.begin(labels)
+	metrics.begin(labels)
+	metrics.end(labels, httpRequest, &Response{StatusCode: http.StatusOK, RawBody: []byte("hello")}, nil, 50*time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf); err != nil {
+		t.Errorf("WriteText() error = %v", err)
+		return
+	}
+
+	want := `# HELP test_requests_total Total number of requests by status class.
+# TYPE test_requests_total counter
+test_requests_total{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 1
+# HELP test_requests_in_flight Number of requests in flight.
+# TYPE test_requests_in_flight gauge
+test_requests_in_flight{host="api\"example.com",method="GET",path="/api/users/{id}"} 1
+# HELP test_request_duration_seconds Request latency in seconds.
+# TYPE test_request_duration_seconds histogram
+test_request_duration_seconds_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="0.1"} 1
+test_request_duration_seconds_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="1"} 1
+test_request_duration_seconds_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="+Inf"} 1
+test_request_duration_seconds_sum{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 0.05
+test_request_duration_seconds_count{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 1
+# HELP test_request_size_bytes Request body size in bytes.
+# TYPE test_request_size_bytes histogram
+test_request_size_bytes_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="10"} 1
+test_request_size_bytes_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="+Inf"} 1
+test_request_size_bytes_sum{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 0
+test_request_size_bytes_count{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 1
+# HELP test_response_size_bytes Response body size in bytes.
+# TYPE test_response_size_bytes histogram
+test_response_size_bytes_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="10"} 1
+test_response_size_bytes_bucket{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx",le="+Inf"} 1
+test_response_size_bytes_sum{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 5
+test_response_size_bytes_count{host="api\"example.com",method="GET",path="/api/users/{id}",status="2xx"} 1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteText() = %v, want %v", got, want)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	metrics, _ := NewMetrics()
+	client, err := newTestClient(server, WithMetrics(metrics))
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+
+	for _, path := range []string{"/api/test", "/api/test", "/api/missing", "/api/users/1", "/api/users/2"} {
+		options := []RequestOption{WithBodyParams(NewJsonBodyParams(map[string]string{"a": "b"}))}
+		if !strings.HasPrefix(path, "/api/users/") {
+			options = append(options, WithPathTemplate(path))
+		}
+		req, _ := NewRequest(http.MethodPost, path, options...)
+		if _, err := client.Do(req); err != nil {
+			t.Errorf("Do() error = %v", err)
+			return
+		}
+	}
+
+	handlerServer := httptest.NewServer(metrics)
+	defer handlerServer.Close()
+	httpResponse, err := http.Get(handlerServer.URL)
+	if err != nil {
+		t.Errorf("Get() error = %v", err)
+		return
+	}
+	defer httpResponse.Body.Close()
+	body, _ := io.ReadAll(httpResponse.Body)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "success count",
+			want: `http_client_requests_total{host="` + host + `",method="POST",path="/api/test",status="2xx"} 2`,
+		},
+		{
+			name: "client error count",
+			want: `http_client_requests_total{host="` + host + `",method="POST",path="/api/missing",status="4xx"} 1`,
+		},
+		{
+			name: "count without path template",
+			want: `http_client_requests_total{host="` + host + `",method="POST",path="unknown",status="2xx"} 2`,
+		},
+		{
+			name: "no request in flight",
+			want: `http_client_requests_in_flight{host="` + host + `",method="POST",path="/api/test"} 0`,
+		},
+		{
+			name: "request size",
+			want: `http_client_request_size_bytes_sum{host="` + host + `",method="POST",path="/api/test",status="2xx"} 18`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(string(body), tt.want) {
+				t.Errorf("ServeHTTP() = %s, want %v", body, tt.want)
+			}
+		})
+	}
+	if got := httpResponse.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("ServeHTTP() Content-Type = %v", got)
+	}
+}
@@ -0,0 +1,356 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrUnexpectedRequest = errors.New("unexpected request")
+
+// TestingT is the part of testing.TB used by MockTransport.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// MockTransport answers requests from expectations instead of sending them.
+// Requests that match no expectation fail with ErrUnexpectedRequest, and
+// expectations that were not met are reported when the test finishes.
+type MockTransport struct {
+	t TestingT
+
+	mu           sync.Mutex
+	ordered      bool
+	next         int
+	expectations []*MockExpectation
+}
+
+func NewMockTransport(t TestingT) *MockTransport {
+	m := &MockTransport{t: t}
+	t.Cleanup(func() {
+		m.AssertExpectations()
+	})
+	return m
+}
+
+// WithMockTransport sends the requests of the client to mock.
+func WithMockTransport(mock *MockTransport) ClientOption {
+	return func(c *Client) error {
+		if mock == nil {
+			return fmt.Errorf("mock transport is nil")
+		}
+		c.baseRoundTripper = mock
+		return nil
+	}
+}
+
+// InOrder makes requests match the expectations in the order they were
+// registered.
+func (m *MockTransport) InOrder() *MockTransport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ordered = true
+	return m
+}
+
+// Expect registers an expectation of one request with method and path.
+func (m *MockTransport) Expect(method, path string) *MockExpectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &MockExpectation{
+		mock:   m,
+		method: method,
+		path:   path,
+		times:  1,
+		respond: func(*http.Request) (*http.Response, error) {
+			return newHTTPResponse(http.StatusOK, nil, nil), nil
+		},
+	}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// AssertExpectations reports the expectations that were not met and returns
+// whether all of them were.
+func (m *MockTransport) AssertExpectations() bool {
+	m.t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	for _, e := range m.expectations {
+		if !e.satisfied() {
+			m.t.Errorf("expected %s to be called %s, called %d times", e, e.timesString(), e.calls)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (m *MockTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request, the body is read from a clone
+	cloned := httpRequest.Clone(httpRequest.Context())
+	body, err := readRequestBody(cloned)
+	if httpRequest.Body != nil {
+		_ = httpRequest.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e := m.match(cloned, body)
+	if e == nil {
+		err = fmt.Errorf("%w %s %s", ErrUnexpectedRequest, httpRequest.Method, httpRequest.URL)
+		m.t.Errorf("%v\n%s", err, m.describe())
+		return nil, err
+	}
+
+	httpResponse, err := e.respond(cloned)
+	if httpResponse != nil && httpResponse.Request == nil {
+		httpResponse.Request = httpRequest
+	}
+	return httpResponse, err
+}
+
+func (m *MockTransport) match(httpRequest *http.Request, body []byte) *MockExpectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.ordered {
+		for _, e := range m.expectations {
+			if !e.exhausted() && e.match(httpRequest, body) {
+				e.calls++
+				return e
+			}
+		}
+		return nil
+	}
+
+	for ; m.next < len(m.expectations); m.next++ {
+		e := m.expectations[m.next]
+		if !e.exhausted() && e.match(httpRequest, body) {
+			e.calls++
+			return e
+		}
+		if !e.satisfied() {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockTransport) describe() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("expectations:")
+	for _, e := range m.expectations {
+		fmt.Fprintf(&b, "\n  %s called %d of %s times", e, e.calls, e.timesString())
+	}
+	return b.String()
+}
+
+// MockExpectation is a request expected by a MockTransport and its response.
+type MockExpectation struct {
+	mock     *MockTransport
+	method   string
+	path     string
+	query    [][2]string
+	headers  [][2]string
+	jsonBody interface{}
+	hasJSON  bool
+	matchers []func(httpRequest *http.Request) bool
+
+	respond  func(httpRequest *http.Request) (*http.Response, error)
+	times    int
+	anyTimes bool
+	calls    int
+}
+
+func (e *MockExpectation) Query(key, value string) *MockExpectation {
+	e.query = append(e.query, [2]string{key, value})
+	return e
+}
+
+func (e *MockExpectation) Header(key, value string) *MockExpectation {
+	e.headers = append(e.headers, [2]string{key, value})
+	return e
+}
+
+// JSONBody matches a JSON request body equal to v, regardless of the order of
+// its keys. When v can not be marshalled the test fails and the expectation
+// matches no request.
+func (e *MockExpectation) JSONBody(v interface{}) *MockExpectation {
+	e.mock.t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.mock.t.Errorf("marshal expected json body of %s error %v", e, err)
+		return e.Matching(func(*http.Request) bool { return false })
+	}
+	_ = json.Unmarshal(data, &e.jsonBody)
+	e.hasJSON = true
+	return e
+}
+
+func (e *MockExpectation) Matching(matcher func(httpRequest *http.Request) bool) *MockExpectation {
+	e.matchers = append(e.matchers, matcher)
+	return e
+}
+
+func (e *MockExpectation) Respond(statusCode int, body []byte) *MockExpectation {
+	return e.respondWith(statusCode, nil, body)
+}
+
+// RespondJSON answers the request with v as JSON. When v can not be
+// marshalled the test fails and the request fails with the error.
+func (e *MockExpectation) RespondJSON(statusCode int, v interface{}) *MockExpectation {
+	e.mock.t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		err = fmt.Errorf("marshal json response error %w", err)
+		e.mock.t.Errorf("%s %v", e, err)
+		return e.RespondError(err)
+	}
+	return e.respondWith(statusCode, http.Header{contentTypeHeader: []string{contentTypeJson}}, data)
+}
+
+func (e *MockExpectation) respondWith(statusCode int, header http.Header, body []byte) *MockExpectation {
+	e.respond = func(*http.Request) (*http.Response, error) {
+		return newHTTPResponse(statusCode, header.Clone(), body), nil
+	}
+	return e
+}
+
+// RespondFunc answers the request with fn.
+func (e *MockExpectation) RespondFunc(fn func(httpRequest *http.Request) (*http.Response, error)) *MockExpectation {
+	e.respond = fn
+	return e
+}
+
+// RespondError fails the request with err.
+func (e *MockExpectation) RespondError(err error) *MockExpectation {
+	e.respond = func(*http.Request) (*http.Response, error) {
+		return nil, err
+	}
+	return e
+}
+
+// Times expects exactly n requests, the default is one.
+func (e *MockExpectation) Times(n int) *MockExpectation {
+	e.times, e.anyTimes = n, false
+	return e
+}
+
+// AnyTimes expects any number of requests, including none.
+func (e *MockExpectation) AnyTimes() *MockExpectation {
+	e.anyTimes = true
+	return e
+}
+
+func (e *MockExpectation) Calls() int {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+
+	return e.calls
+}
+
+func (e *MockExpectation) String() string {
+	var b strings.Builder
+	b.WriteString(e.method + " " + e.path)
+	for _, query := range e.query {
+		b.WriteString(" query " + query[0] + "=" + query[1])
+	}
+	for _, header := range e.headers {
+		b.WriteString(" header " + header[0] + ": " + header[1])
+	}
+	if e.hasJSON {
+		data, _ := json.Marshal(e.jsonBody)
+		b.WriteString(" json " + string(data))
+	}
+	return b.String()
+}
+
+func (e *MockExpectation) timesString() string {
+	if e.anyTimes {
+		return "any"
+	}
+	return strconv.Itoa(e.times)
+}
+
+func (e *MockExpectation) exhausted() bool {
+	return !e.anyTimes && e.calls >= e.times
+}
+
+func (e *MockExpectation) satisfied() bool {
+	return e.anyTimes || e.calls == e.times
+}
+
+func (e *MockExpectation) match(httpRequest *http.Request, body []byte) bool {
+	if httpRequest.Method != e.method || httpRequest.URL.Path != e.path {
+		return false
+	}
+
+	query := httpRequest.URL.Query()
+	for _, q := range e.query {
+		if !containsString(query[q[0]], q[1]) {
+			return false
+		}
+	}
+	for _, header := range e.headers {
+		if !containsString(httpRequest.Header.Values(header[0]), header[1]) {
+			return false
+		}
+	}
+	if e.hasJSON {
+		var jsonBody interface{}
+		if json.Unmarshal(body, &jsonBody) != nil || !reflect.DeepEqual(jsonBody, e.jsonBody) {
+			return false
+		}
+	}
+	for _, matcher := range e.matchers {
+		if !matcher(httpRequest) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func newHTTPResponse(statusCode int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
@@ -0,0 +1,236 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type fakeTestingT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeTestingT) Helper() {}
+
+func (t *fakeTestingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeTestingT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+func (t *fakeTestingT) finish() {
+	for _, fn := range t.cleanups {
+		fn()
+	}
+}
+
+func TestWithMockTransport(t *testing.T) {
+	mock := NewMockTransport(t)
+	mock.Expect(http.MethodGet, "/users/1").
+		Query("fields", "name").
+		Header("X-Version", "2").
+		RespondJSON(http.StatusOK, map[string]string{"name": "amu"})
+	mock.Expect(http.MethodPost, "/users").
+		JSONBody(map[string]interface{}{"name": "wall", "age": 3}).
+		Respond(http.StatusCreated, []byte("created")).
+		Times(2)
+
+	client, err := NewClient("api.example.com", WithMockTransport(mock))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	req, _ := NewRequest(
+		http.MethodGet, "/users/1",
+		WithHeaders(map[string]string{"X-Version": "2"}),
+		WithQueryParams(NewQueryParams(map[string]string{"fields": "name"})),
+	)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.RawBody) != `{"name":"amu"}` {
+		t.Errorf("Do() = %v %s", resp.StatusCode, resp.RawBody)
+	}
+
+	for i := 0; i < 2; i++ {
+		req, _ = NewRequest(
+			http.MethodPost, "/users",
+			WithHeaders(map[string]string{contentTypeHeader: contentTypeJson}),
+			WithBodyParams(NewJsonBodyParams(map[string]interface{}{"age": 3, "name": "wall"})),
+		)
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if resp.StatusCode != http.StatusCreated || string(resp.RawBody) != "created" {
+			t.Errorf("Do() = %v %s", resp.StatusCode, resp.RawBody)
+		}
+	}
+}
+
+func TestMockTransport_AssertExpectations(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+	}
+	tests := []struct {
+		name       string
+		setup      func(mock *MockTransport)
+		requests   []request
+		wantErrors []string
+	}{
+		{
+			name: "all expectations met",
+			setup: func(mock *MockTransport) {
+				mock.Expect(http.MethodGet, "/a")
+				mock.Expect(http.MethodGet, "/b").AnyTimes()
+			},
+			requests: []request{{http.MethodGet, "/a"}},
+		},
+		{
+			name: "expectation not met",
+			setup: func(mock *MockTransport) {
+				mock.Expect(http.MethodGet, "/a").Times(2)
+			},
+			requests:   []request{{http.MethodGet, "/a"}},
+			wantErrors: []string{"expected GET /a to be called 2, called 1 times"},
+		},
+		{
+			name: "unexpected request",
+			setup: func(mock *MockTransport) {
+				mock.Expect(http.MethodGet, "/a")
+			},
+			requests: []request{{http.MethodGet, "/a"}, {http.MethodGet, "/a"}},
+			wantErrors: []string{
+				"unexpected request GET https://api.example.com:443/a\nexpectations:\n  GET /a called 1 of 1 times",
+			},
+		},
+		{
+			name: "unordered expectations",
+			setup: func(mock *MockTransport) {
+				mock.Expect(http.MethodGet, "/a")
+				mock.Expect(http.MethodGet, "/b")
+			},
+			requests: []request{{http.MethodGet, "/b"}, {http.MethodGet, "/a"}},
+		},
+		{
+			name: "ordered expectations",
+			setup: func(mock *MockTransport) {
+				mock.InOrder()
+				mock.Expect(http.MethodGet, "/a").AnyTimes()
+				mock.Expect(http.MethodGet, "/b").Times(2)
+				mock.Expect(http.MethodGet, "/c")
+			},
+			requests: []request{{http.MethodGet, "/a"}, {http.MethodGet, "/b"}, {http.MethodGet, "/b"}, {http.MethodGet, "/c"}},
+		},
+		{
+			name: "ordered expectations out of order",
+			setup: func(mock *MockTransport) {
+				mock.InOrder()
+				mock.Expect(http.MethodGet, "/a")
+				mock.Expect(http.MethodGet, "/b")
+			},
+			requests: []request{{http.MethodGet, "/b"}, {http.MethodGet, "/a"}},
+			wantErrors: []string{
+				"unexpected request GET https://api.example.com:443/b\nexpectations:\n  GET /a called 0 of 1 times\n  GET /b called 0 of 1 times",
+				"expected GET /b to be called 1, called 0 times",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeT := &fakeTestingT{}
+			mock := NewMockTransport(fakeT)
+			tt.setup(mock)
+			client, _ := NewClient("api.example.com", WithMockTransport(mock))
+
+			for _, r := range tt.requests {
+				req, _ := NewRequest(r.method, r.path)
+				_, err := client.Do(req)
+				if err != nil && !errors.Is(err, ErrUnexpectedRequest) {
+					t.Errorf("Do() error = %v", err)
+				}
+			}
+			fakeT.finish()
+
+			if strings.Join(fakeT.errors, "|") != strings.Join(tt.wantErrors, "|") {
+				t.Errorf("errors = %q, want %q", fakeT.errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestMockExpectation_RespondFunc(t *testing.T) {
+	mock := NewMockTransport(t)
+	respondErr := errors.New("connection reset")
+	mock.Expect(http.MethodGet, "/error").RespondError(respondErr)
+	mock.Expect(http.MethodGet, "/func").
+		Matching(func(httpRequest *http.Request) bool {
+			return httpRequest.Header.Get("X-Id") != ""
+		}).
+		RespondFunc(func(httpRequest *http.Request) (*http.Response, error) {
+			return newHTTPResponse(http.StatusAccepted, nil, []byte(httpRequest.Header.Get("X-Id"))), nil
+		})
+	client, _ := NewClient("api.example.com", WithMockTransport(mock))
+
+	req, _ := NewRequest(http.MethodGet, "/error")
+	_, err := client.Do(req)
+	if !errors.Is(err, respondErr) {
+		t.Errorf("Do() error = %v, want %v", err, respondErr)
+	}
+
+	req, _ = NewRequest(http.MethodGet, "/func", WithHeaders(map[string]string{"X-Id": "42"}))
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusAccepted || string(resp.RawBody) != "42" {
+		t.Errorf("Do() = %v, %v", resp, err)
+	}
+}
+
+func TestMockExpectation_marshalError(t *testing.T) {
+	fakeT := &fakeTestingT{}
+	mock := NewMockTransport(fakeT)
+	mock.Expect(http.MethodPost, "/body").JSONBody(make(chan int)).AnyTimes()
+	mock.Expect(http.MethodGet, "/response").RespondJSON(http.StatusOK, make(chan int))
+	client, _ := NewClient("api.example.com", WithMockTransport(mock))
+
+	req, _ := NewRequest(http.MethodGet, "/response")
+	_, err := client.Do(req)
+	if err == nil || !strings.Contains(err.Error(), "marshal json response error") {
+		t.Errorf("Do() error = %v, want marshal json response error", err)
+	}
+	fakeT.finish()
+
+	wantErrors := []string{
+		"marshal expected json body of POST /body error json: unsupported type: chan int",
+		"GET /response marshal json response error json: unsupported type: chan int",
+	}
+	if strings.Join(fakeT.errors, "|") != strings.Join(wantErrors, "|") {
+		t.Errorf("errors = %q, want %q", fakeT.errors, wantErrors)
+	}
+}
+
+func TestMockTransport_RoundTrip_request(t *testing.T) {
+	mock := NewMockTransport(t)
+	mock.Expect(http.MethodPost, "/a").JSONBody(map[string]int{"id": 1})
+
+	body := io.NopCloser(strings.NewReader(`{"id":1}`))
+	httpRequest, _ := http.NewRequest(http.MethodPost, "https://api.example.com/a", body)
+	resp, err := mock.RoundTrip(httpRequest)
+	if err != nil {
+		t.Errorf("RoundTrip() error = %v", err)
+		return
+	}
+	if httpRequest.Body != body || httpRequest.GetBody != nil {
+		t.Errorf("RoundTrip() modified the request body")
+	}
+	if resp.Request != httpRequest {
+		t.Errorf("RoundTrip() response request = %v, want %v", resp.Request, httpRequest)
+	}
+}
@@ -122,3 +122,20 @@ func (p *FormBodyParams) Build() (contentType string, body io.Reader, err error)
 
 	return
 }
+
+// RawBodyParams sends data as is with the given content type.
+type RawBodyParams struct {
+	contentType string
+	data        []byte
+}
+
+func NewRawBodyParams(contentType string, data []byte) *RawBodyParams {
+	return &RawBodyParams{
+		contentType: contentType,
+		data:        data,
+	}
+}
+
+func (p *RawBodyParams) Build() (contentType string, body io.Reader, err error) {
+	return p.contentType, bytes.NewReader(p.data), nil
+}
@@ -387,3 +387,48 @@ func TestFormBodyParams_Build(t *testing.T) {
 		})
 	}
 }
+
+func TestRawBodyParams_Build(t *testing.T) {
+	type fields struct {
+		contentType string
+		data        []byte
+	}
+	tests := []struct {
+		name            string
+		fields          fields
+		wantContentType string
+		wantBodyData    []byte
+	}{
+		{
+			name: "build raw params",
+			fields: fields{
+				contentType: "text/plain",
+				data:        []byte("hello"),
+			},
+			wantContentType: "text/plain",
+			wantBodyData:    []byte("hello"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewRawBodyParams(tt.fields.contentType, tt.fields.data)
+			gotContentType, gotBody, err := p.Build()
+			if err != nil {
+				t.Errorf("Build() error = %v", err)
+				return
+			}
+			if gotContentType != tt.wantContentType {
+				t.Errorf("Build() gotContentType = %v, want %v", gotContentType, tt.wantContentType)
+				return
+			}
+			gotBodyData, err := io.ReadAll(gotBody)
+			if err != nil {
+				t.Errorf("Build() read from body error %v", err)
+				return
+			}
+			if !reflect.DeepEqual(gotBodyData, tt.wantBodyData) {
+				t.Errorf("Build() gotBody data = %v, want %v", string(gotBodyData), string(tt.wantBodyData))
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type proxyConfig struct {
+	url             *url.URL
+	fromEnvironment bool
+	bypass          []string
+}
+
+func (c *Client) proxyConfig() *proxyConfig {
+	if c.proxy == nil {
+		c.proxy = &proxyConfig{}
+	}
+	c.transport.Proxy = c.proxy.proxyURL
+	return c.proxy
+}
+
+// WithProxy sends requests through an http, https or socks5 proxy. Credentials
+// in the URL are used for proxy authentication.
+func WithProxy(proxyURL string) ClientOption {
+	return func(c *Client) error {
+		u, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return err
+		}
+		c.proxyConfig().url = u
+		return nil
+	}
+}
+
+// WithProxyFromEnvironment reads HTTP_PROXY, HTTPS_PROXY and NO_PROXY on every
+// request. A proxy set by WithProxy takes precedence.
+func WithProxyFromEnvironment() ClientOption {
+	return func(c *Client) error {
+		c.proxyConfig().fromEnvironment = true
+		return nil
+	}
+}
+
+// WithProxyBypass sets hosts reached without proxy, in NO_PROXY syntax:
+// "*", "example.com", ".example.com", "example.com:8080", "10.0.0.1" or "10.0.0.0/8".
+func WithProxyBypass(hosts ...string) ClientOption {
+	return func(c *Client) error {
+		p := c.proxyConfig()
+		p.bypass = append(p.bypass, parseProxyBypass(strings.Join(hosts, ","))...)
+		return nil
+	}
+}
+
+func WithProxyConnectHeaders(headers map[string]string) ClientOption {
+	return func(c *Client) error {
+		if c.transport.ProxyConnectHeader == nil {
+			c.transport.ProxyConnectHeader = http.Header{}
+		}
+		for k, v := range headers {
+			c.transport.ProxyConnectHeader.Set(k, v)
+		}
+		return nil
+	}
+}
+
+func (p *proxyConfig) proxyURL(httpRequest *http.Request) (*url.URL, error) {
+	if matchProxyBypass(p.bypass, httpRequest.URL) {
+		return nil, nil
+	}
+
+	if p.url != nil {
+		return p.url, nil
+	}
+
+	if !p.fromEnvironment {
+		return nil, nil
+	}
+
+	if matchProxyBypass(parseProxyBypass(getEnvAny("NO_PROXY", "no_proxy")), httpRequest.URL) {
+		return nil, nil
+	}
+
+	var proxy string
+	switch httpRequest.URL.Scheme {
+	case "https":
+		proxy = getEnvAny("HTTPS_PROXY", "https_proxy")
+	case "http":
+		proxy = getEnvAny("HTTP_PROXY", "http_proxy")
+	}
+	if proxy == "" {
+		return nil, nil
+	}
+
+	return parseProxyURL(proxy)
+}
+
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy url error %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %s without host", proxyURL)
+	}
+
+	return u, nil
+}
+
+func parseProxyBypass(value string) (entries []string) {
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		entries = append(entries, strings.ToLower(entry))
+	}
+	return
+}
+
+func matchProxyBypass(entries []string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = defaultPortOf(u.Scheme)
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range entries {
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		if strings.HasPrefix(entryHost, ".") {
+			if strings.HasSuffix(host, entryHost) {
+				return true
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func defaultPortOf(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,349 @@
+package request
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestHTTPProxy(t *testing.T, wantAuthorization string) (*httptest.Server, *int32) {
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != wantAuthorization {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		atomic.AddInt32(&proxied, 1)
+
+		if r.Method == http.MethodConnect {
+			if r.Header.Get("X-Connect-Token") != "token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			target, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			go pipeConn(conn, target)
+			return
+		}
+
+		r.RequestURI = ""
+		r.Header.Del("Proxy-Authorization")
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("X-Proxied", "true")
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &proxied
+}
+
+func newTestSOCKS5Proxy(t *testing.T, username, password string) (string, *int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	var proxied int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				target, err := socks5Handshake(conn, username, password)
+				if err != nil {
+					_ = conn.Close()
+					return
+				}
+				atomic.AddInt32(&proxied, 1)
+				pipeConn(conn, target)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), &proxied
+}
+
+func socks5Handshake(conn net.Conn, username, password string) (net.Conn, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 2}); err != nil {
+		return nil, err
+	}
+
+	version := make([]byte, 2)
+	if _, err := io.ReadFull(conn, version); err != nil {
+		return nil, err
+	}
+	user := make([]byte, version[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return nil, err
+	}
+	passwordLength := make([]byte, 1)
+	if _, err := io.ReadFull(conn, passwordLength); err != nil {
+		return nil, err
+	}
+	pass := make([]byte, passwordLength[0])
+	if _, err := io.ReadFull(conn, pass); err != nil {
+		return nil, err
+	}
+	if string(user) != username || string(pass) != password {
+		_, _ = conn.Write([]byte{1, 1})
+		return nil, io.ErrUnexpectedEOF
+	}
+	if _, err := conn.Write([]byte{1, 0}); err != nil {
+		return nil, err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return nil, err
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return nil, err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return nil, err
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		return nil, io.ErrUnexpectedEOF
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return nil, err
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		_ = target.Close()
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func pipeConn(a, b net.Conn) {
+	go func() {
+		_, _ = io.Copy(a, b)
+		_ = a.Close()
+	}()
+	_, _ = io.Copy(b, a)
+	_ = b.Close()
+}
+
+func TestWithProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	httpProxy, httpProxied := newTestHTTPProxy(t, "Basic dXNlcjpwYXNz")
+	socksProxy, socksProxied := newTestSOCKS5Proxy(t, "user", "pass")
+	proxyURL, _ := url.Parse(httpProxy.URL)
+	proxyURL.User = url.UserPassword("user", "pass")
+
+	tests := []struct {
+		name        string
+		server      *httptest.Server
+		options     []ClientOption
+		proxied     *int32
+		wantProxied bool
+	}{
+		{
+			name:        "http proxy",
+			server:      server,
+			options:     []ClientOption{WithProxy(proxyURL.String())},
+			proxied:     httpProxied,
+			wantProxied: true,
+		},
+		{
+			name:   "https proxy with connect",
+			server: tlsServer,
+			options: []ClientOption{
+				WithSkipVerifyCertificates(),
+				WithProxy(proxyURL.String()),
+				WithProxyConnectHeaders(map[string]string{"X-Connect-Token": "token"}),
+			},
+			proxied:     httpProxied,
+			wantProxied: true,
+		},
+		{
+			name:        "socks5 proxy with authentication",
+			server:      server,
+			options:     []ClientOption{WithProxy("socks5://user:pass@" + socksProxy)},
+			proxied:     socksProxied,
+			wantProxied: true,
+		},
+		{
+			name:   "bypass proxy",
+			server: server,
+			options: []ClientOption{
+				WithProxy(proxyURL.String()),
+				WithProxyBypass("127.0.0.0/8"),
+			},
+			proxied:     httpProxied,
+			wantProxied: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newTestClient(tt.server, tt.options...)
+			if err != nil {
+				t.Errorf("NewClient() error = %v", err)
+				return
+			}
+			before := atomic.LoadInt32(tt.proxied)
+
+			req, _ := NewRequest(http.MethodGet, "/api/test")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Do() StatusCode = %v, want %v", resp.StatusCode, http.StatusOK)
+			}
+			if gotProxied := atomic.LoadInt32(tt.proxied) > before; gotProxied != tt.wantProxied {
+				t.Errorf("Do() proxied = %v, want %v", gotProxied, tt.wantProxied)
+			}
+		})
+	}
+}
+
+func TestWithProxyFromEnvironment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	proxy, _ := newTestHTTPProxy(t, "")
+
+	tests := []struct {
+		name        string
+		noProxy     string
+		wantProxied string
+	}{
+		{
+			name:        "proxy from environment",
+			noProxy:     "",
+			wantProxied: "true",
+		},
+		{
+			name:        "no proxy from environment",
+			noProxy:     "example.com,127.0.0.1",
+			wantProxied: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HTTP_PROXY", proxy.URL)
+			t.Setenv("NO_PROXY", tt.noProxy)
+
+			client, _ := newTestClient(server, WithProxyFromEnvironment())
+			req, _ := NewRequest(http.MethodGet, "/api/test")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			if got := resp.Header.Get("X-Proxied"); got != tt.wantProxied {
+				t.Errorf("Do() proxied = %v, want %v", got, tt.wantProxied)
+			}
+		})
+	}
+}
+
+func Test_parseProxyURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxyURL string
+		want     string
+		wantErr  bool
+	}{
+		{name: "http proxy", proxyURL: "http://proxy:8080", want: "http://proxy:8080"},
+		{name: "proxy without scheme", proxyURL: "proxy:8080", want: "http://proxy:8080"},
+		{name: "socks5 proxy", proxyURL: "socks5://u:p@proxy:1080", want: "socks5://u:p@proxy:1080"},
+		{name: "unsupported proxy", proxyURL: "ftp://proxy:21", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProxyURL(tt.proxyURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseProxyURL() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("parseProxyURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchProxyBypass(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries string
+		url     string
+		want    bool
+	}{
+		{name: "match all", entries: "*", url: "https://example.com", want: true},
+		{name: "match domain", entries: "example.com", url: "https://example.com", want: true},
+		{name: "match subdomain", entries: "example.com", url: "https://api.example.com", want: true},
+		{name: "match dot subdomain", entries: ".example.com", url: "https://api.example.com", want: true},
+		{name: "not match dot domain", entries: ".example.com", url: "https://example.com", want: false},
+		{name: "not match suffix", entries: "example.com", url: "https://badexample.com", want: false},
+		{name: "match port", entries: "example.com:8080", url: "http://example.com:8080", want: true},
+		{name: "not match port", entries: "example.com:8080", url: "https://example.com", want: false},
+		{name: "match default port", entries: "example.com:443", url: "https://example.com", want: true},
+		{name: "match ip", entries: "10.0.0.1", url: "http://10.0.0.1:8080", want: true},
+		{name: "match cidr", entries: "foo.com, 10.0.0.0/8", url: "http://10.1.2.3", want: true},
+		{name: "not match cidr", entries: "10.0.0.0/8", url: "http://192.168.1.1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			if got := matchProxyBypass(parseProxyBypass(tt.entries), u); got != tt.want {
+				t.Errorf("matchProxyBypass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+type Redirect struct {
+	URL        string
+	StatusCode int
+}
+
+type redirectPolicy struct {
+	maxRedirects int
+	disabled     bool
+	sameHost     bool
+
+	// nil keeps the net/http behavior of stripping Authorization when the
+	// redirect leaves the original domain.
+	preserveAuthorization *bool
+}
+
+const defaultMaxRedirects = 10
+
+func (c *Client) redirectPolicy() *redirectPolicy {
+	if c.redirect == nil {
+		c.redirect = &redirectPolicy{
+			maxRedirects: defaultMaxRedirects,
+		}
+		c.instance.CheckRedirect = c.redirect.check
+	}
+	return c.redirect
+}
+
+func WithMaxRedirects(maxRedirects int) ClientOption {
+	return func(c *Client) error {
+		if maxRedirects < 0 {
+			return fmt.Errorf("invalid max redirects %d", maxRedirects)
+		}
+		c.redirectPolicy().maxRedirects = maxRedirects
+		return nil
+	}
+}
+
+// WithoutRedirects makes the client return 3xx responses as they are.
+func WithoutRedirects() ClientOption {
+	return func(c *Client) error {
+		c.redirectPolicy().disabled = true
+		return nil
+	}
+}
+
+// WithSameHostRedirects follows redirects only within the original host, a
+// redirect to another host is returned as the response.
+func WithSameHostRedirects() ClientOption {
+	return func(c *Client) error {
+		c.redirectPolicy().sameHost = true
+		return nil
+	}
+}
+
+// WithRedirectAuthorization controls the Authorization header when a redirect
+// changes host, it is either kept or always removed.
+func WithRedirectAuthorization(preserve bool) ClientOption {
+	return func(c *Client) error {
+		c.redirectPolicy().preserveAuthorization = &preserve
+		return nil
+	}
+}
+
+func (p *redirectPolicy) check(req *http.Request, via []*http.Request) error {
+	if p.disabled {
+		return http.ErrUseLastResponse
+	}
+
+	if len(via) > p.maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, p.maxRedirects)
+	}
+
+	if req.URL.Host == via[0].URL.Host {
+		return nil
+	}
+
+	if p.sameHost {
+		return http.ErrUseLastResponse
+	}
+
+	if p.preserveAuthorization != nil {
+		if *p.preserveAuthorization {
+			if authorization := via[0].Header.Get(authorizationHeader); authorization != "" {
+				req.Header.Set(authorizationHeader, authorization)
+			}
+		} else {
+			req.Header.Del(authorizationHeader)
+		}
+	}
+
+	return nil
+}
+
+func parseRedirects(httpResponse *http.Response) (redirects []Redirect) {
+	httpRequest := httpResponse.Request
+	for httpRequest != nil && httpRequest.Response != nil {
+		previous := httpRequest.Response
+		if previous.Request == nil {
+			break
+		}
+		redirects = append([]Redirect{{
+			URL:        previous.Request.URL.String(),
+			StatusCode: previous.StatusCode,
+		}}, redirects...)
+		httpRequest = previous.Request
+	}
+	return
+}
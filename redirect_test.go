@@ -0,0 +1,137 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Do_redirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		case "/c":
+			w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, other.URL+"/target", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+	serverURL = server.URL
+
+	tests := []struct {
+		name              string
+		options           []ClientOption
+		path              string
+		wantErr           error
+		wantStatusCode    int
+		wantRedirects     []Redirect
+		wantAuthorization string
+	}{
+		{
+			name:           "follow redirects",
+			options:        nil,
+			path:           "/a",
+			wantStatusCode: http.StatusOK,
+			wantRedirects: []Redirect{
+				{URL: serverURL + "/a", StatusCode: http.StatusFound},
+				{URL: serverURL + "/b", StatusCode: http.StatusMovedPermanently},
+			},
+			wantAuthorization: "Bearer token",
+		},
+		{
+			name:           "disable redirects",
+			options:        []ClientOption{WithoutRedirects()},
+			path:           "/a",
+			wantStatusCode: http.StatusFound,
+			wantRedirects:  nil,
+		},
+		{
+			name:    "max redirects",
+			options: []ClientOption{WithMaxRedirects(1)},
+			path:    "/a",
+			wantErr: ErrTooManyRedirects,
+		},
+		{
+			name:    "redirect loop",
+			options: []ClientOption{WithMaxRedirects(3)},
+			path:    "/loop",
+			wantErr: ErrTooManyRedirects,
+		},
+		{
+			name:           "same host redirects",
+			options:        []ClientOption{WithSameHostRedirects()},
+			path:           "/other",
+			wantStatusCode: http.StatusTemporaryRedirect,
+			wantRedirects:  nil,
+		},
+		{
+			name:           "preserve authorization across hosts",
+			options:        []ClientOption{WithRedirectAuthorization(true)},
+			path:           "/other",
+			wantStatusCode: http.StatusOK,
+			wantRedirects: []Redirect{
+				{URL: serverURL + "/other", StatusCode: http.StatusTemporaryRedirect},
+			},
+			wantAuthorization: "Bearer token",
+		},
+		{
+			name:           "strip authorization across hosts",
+			options:        []ClientOption{WithRedirectAuthorization(false)},
+			path:           "/other",
+			wantStatusCode: http.StatusOK,
+			wantRedirects: []Redirect{
+				{URL: serverURL + "/other", StatusCode: http.StatusTemporaryRedirect},
+			},
+			wantAuthorization: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(server, tt.options...)
+			req, _ := NewRequest(
+				http.MethodGet,
+				tt.path,
+				WithHeaders(map[string]string{"Authorization": "Bearer token"}),
+			)
+
+			resp, err := client.Do(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("Do() StatusCode = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+			if !reflect.DeepEqual(resp.Redirects, tt.wantRedirects) {
+				t.Errorf("Do() Redirects = %v, want %v", resp.Redirects, tt.wantRedirects)
+			}
+			if got := resp.Header.Get("X-Authorization"); got != tt.wantAuthorization {
+				t.Errorf("Do() Authorization = %v, want %v", got, tt.wantAuthorization)
+			}
+		})
+	}
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	_, err := NewClient("127.0.0.1", WithMaxRedirects(-1))
+	if err == nil {
+		t.Errorf("WithMaxRedirects() error = %v, wantErr true", err)
+	}
+}
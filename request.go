@@ -1,6 +1,8 @@
 package request
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,11 +13,21 @@ type Request struct {
 	Method string
 	Path   string
 
+	// PathTemplate, like /users/{id}, labels the request in metrics, which
+	// label requests without it by "unknown".
+	PathTemplate string
+
 	Host    string
 	Headers http.Header
 
 	QueryParams QueryParams
 	BodyParams  BodyParams
+
+	Cookies []*http.Cookie
+
+	BalanceKey string
+
+	ctx context.Context
 }
 
 type RequestOption func(*Request) error
@@ -39,6 +51,16 @@ func NewRequest(method, path string, options ...RequestOption) (request *Request
 	return
 }
 
+func WithContext(ctx context.Context) RequestOption {
+	return func(r *Request) error {
+		if ctx == nil {
+			return errors.New("nil context")
+		}
+		r.ctx = ctx
+		return nil
+	}
+}
+
 func WithHost(host string) RequestOption {
 	return func(r *Request) error {
 		r.Host = host
@@ -55,6 +77,29 @@ func WithHeaders(headers map[string]string) RequestOption {
 	}
 }
 
+func WithCookies(cookies ...*http.Cookie) RequestOption {
+	return func(r *Request) error {
+		r.Cookies = append(r.Cookies, cookies...)
+		return nil
+	}
+}
+
+// WithBalanceKey sets the key used to pick an endpoint with ConsistentHash.
+func WithBalanceKey(key string) RequestOption {
+	return func(r *Request) error {
+		r.BalanceKey = key
+		return nil
+	}
+}
+
+// WithPathTemplate is required to label the request by path in metrics.
+func WithPathTemplate(template string) RequestOption {
+	return func(r *Request) error {
+		r.PathTemplate = template
+		return nil
+	}
+}
+
 func WithQueryParams(queryParams QueryParams) RequestOption {
 	return func(r *Request) error {
 		r.QueryParams = queryParams
@@ -69,6 +114,13 @@ func WithBodyParams(bodyParams BodyParams) RequestOption {
 	}
 }
 
+func (req *Request) Context() context.Context {
+	if req.ctx != nil {
+		return req.ctx
+	}
+	return context.Background()
+}
+
 func (req *Request) build(baseURL string) (httpRequest *http.Request, err error) {
 	requestURL, err := url.JoinPath(baseURL, req.Path)
 	if err != nil {
@@ -91,7 +143,7 @@ func (req *Request) build(baseURL string) (httpRequest *http.Request, err error)
 		}
 	}
 
-	httpRequest, err = http.NewRequest(req.Method, requestURL, requestBody)
+	httpRequest, err = http.NewRequestWithContext(req.Context(), req.Method, requestURL, requestBody)
 	if err != nil {
 		err = fmt.Errorf("new http request error %w", err)
 		return
@@ -102,7 +154,11 @@ func (req *Request) build(baseURL string) (httpRequest *http.Request, err error)
 	}
 
 	if req.Headers != nil {
-		httpRequest.Header = req.Headers
+		httpRequest.Header = req.Headers.Clone()
+	}
+
+	for _, cookie := range req.Cookies {
+		httpRequest.AddCookie(cookie)
 	}
 
 	if len(req.QueryParams) != 0 {
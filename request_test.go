@@ -1,6 +1,7 @@
 package request
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -285,3 +286,102 @@ func TestRequest_build(t *testing.T) {
 		})
 	}
 }
+
+func TestWithCookies(t *testing.T) {
+	type args struct {
+		cookies []*http.Cookie
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "init request with cookies",
+			args: args{
+				cookies: []*http.Cookie{
+					{Name: "session", Value: "token"},
+					{Name: "lang", Value: "en"},
+				},
+			},
+			want: "session=token; lang=en",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := NewRequest(http.MethodGet, "/api/test", WithCookies(tt.args.cookies...))
+			httpRequest, _ := request.build("https://127.0.0.1")
+			if got := httpRequest.Header.Get("Cookie"); got != tt.want {
+				t.Errorf("WithCookies() = %v, want %v", got, tt.want)
+			}
+			if request.Headers.Get("Cookie") != "" {
+				t.Errorf("WithCookies() modified request headers %v", request.Headers)
+			}
+		})
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    context.Context
+		wantErr bool
+	}{
+		{
+			name: "init request with context",
+			ctx:  ctx,
+			want: ctx,
+		},
+		{
+			name:    "init request with nil context",
+			ctx:     nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := NewRequest(http.MethodGet, "/api/test", WithContext(tt.ctx))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithContext() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			httpRequest, _ := request.build("https://127.0.0.1")
+			if request.Context() != tt.want || httpRequest.Context() != tt.want {
+				t.Errorf("WithContext() = %v, want %v", request.Context(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPathTemplate(t *testing.T) {
+	type args struct {
+		template string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "init request with path template",
+			args: args{
+				template: "/api/users/{id}",
+			},
+			want: "/api/users/{id}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := NewRequest(http.MethodGet, "/api/users/1", WithPathTemplate(tt.args.template))
+			if request.PathTemplate != tt.want {
+				t.Errorf("WithPathTemplate() = %v, want %v", request.PathTemplate, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package requesttest
+
+import (
+	"strings"
+)
+
+const diffContext = 3
+
+// Diff returns a line diff from want to got, lines only in want are prefixed
+// with - and lines only in got with +. Unchanged lines far from changes are
+// elided.
+func Diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// lengths[i][j] is the longest common subsequence of wantLines[i:] and
+	// gotLines[j:].
+	lengths := make([][]int, len(wantLines)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(gotLines)+1)
+	}
+	for i := len(wantLines) - 1; i >= 0; i-- {
+		for j := len(gotLines) - 1; j >= 0; j-- {
+			if wantLines[i] == gotLines[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(wantLines) || j < len(gotLines) {
+		switch {
+		case i < len(wantLines) && j < len(gotLines) && wantLines[i] == gotLines[j]:
+			lines = append(lines, line{' ', wantLines[i]})
+			i++
+			j++
+		case i < len(wantLines) && (j == len(gotLines) || lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, line{'-', wantLines[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', gotLines[j]})
+			j++
+		}
+	}
+
+	near := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for n := max(0, k-diffContext); n <= min(len(lines)-1, k+diffContext); n++ {
+			near[n] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("--- want\n+++ got\n")
+	elided := false
+	for k, l := range lines {
+		if !near[k] {
+			if !elided {
+				b.WriteString("  ...\n")
+				elided = true
+			}
+			continue
+		}
+		elided = false
+		b.WriteByte(l.op)
+		b.WriteByte(' ')
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
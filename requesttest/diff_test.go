@@ -0,0 +1,45 @@
+package requesttest
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type args struct {
+		want string
+		got  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "diff equal",
+			args: args{want: "a\nb", got: "a\nb"},
+			want: "--- want\n+++ got\n  ...\n",
+		},
+		{
+			name: "diff changed line",
+			args: args{want: "a\nb\nc", got: "a\nx\nc"},
+			want: "--- want\n+++ got\n  a\n- b\n+ x\n  c\n",
+		},
+		{
+			name: "diff added and removed lines",
+			args: args{want: "a\nb", got: "b\nc"},
+			want: "--- want\n+++ got\n- a\n  b\n+ c\n",
+		},
+		{
+			name: "diff elided lines",
+			args: args{want: "1\n2\n3\n4\n5\n6\n7\n8\n9", got: "1\n2\n3\n4\n5\n6\n7\n8\nx"},
+			want: "--- want\n+++ got\n  ...\n  6\n  7\n  8\n- 9\n+ x\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.args.want, tt.args.got); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+// Package requesttest provides assertions on responses of the request package
+// for tests.
+package requesttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/amuwall/go-request"
+)
+
+var update = flag.Bool("requesttest.update", false, "update golden files of requesttest")
+
+func AssertStatus(t testing.TB, resp *request.Response, statusCode int) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	if resp.StatusCode != statusCode {
+		t.Errorf("status code = %d, want %d\n%s", resp.StatusCode, statusCode, bodySnippet(resp))
+		return false
+	}
+	return true
+}
+
+// AssertStatusClass asserts the class of the status code, for example 2 for
+// 2xx.
+func AssertStatusClass(t testing.TB, resp *request.Response, class int) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	if resp.StatusCode/100 != class {
+		t.Errorf("status code = %d, want %dxx\n%s", resp.StatusCode, class, bodySnippet(resp))
+		return false
+	}
+	return true
+}
+
+func AssertHeader(t testing.TB, resp *request.Response, name string) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	if len(resp.Header.Values(name)) == 0 {
+		t.Errorf("header %s is missing, headers are %v", name, resp.Header)
+		return false
+	}
+	return true
+}
+
+// AssertHeaderMatches asserts that a value of the header matches the regular
+// expression pattern.
+func AssertHeaderMatches(t testing.TB, resp *request.Response, name, pattern string) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Errorf("invalid header pattern %s: %v", pattern, err)
+		return false
+	}
+	values := resp.Header.Values(name)
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	t.Errorf("header %s = %q, want match of %s", name, values, pattern)
+	return false
+}
+
+// AssertJSONEqual asserts that the body is JSON equal to expected, regardless
+// of the order of object keys. Expected is JSON when it is a string or a byte
+// slice, any other value is marshaled. Ignored paths are dot-separated keys or
+// array indexes, * matches any key or element.
+func AssertJSONEqual(t testing.TB, resp *request.Response, expected interface{}, ignoredPaths ...string) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		t.Errorf("invalid expected json: %v", err)
+		return false
+	}
+	var got interface{}
+	err = json.Unmarshal(resp.RawBody, &got)
+	if err != nil {
+		t.Errorf("invalid json body: %v\n%s", err, bodySnippet(resp))
+		return false
+	}
+
+	for _, path := range ignoredPaths {
+		keys := strings.Split(path, ".")
+		want = deletePath(want, keys)
+		got = deletePath(got, keys)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json body not equal:\n%s", Diff(indentJSON(want), indentJSON(got)))
+		return false
+	}
+	return true
+}
+
+// AssertJSONPath asserts the value at a dot-separated path of the JSON body,
+// for example users.0.name. Expected is a value, a string is not parsed as
+// JSON.
+func AssertJSONPath(t testing.TB, resp *request.Response, path string, expected interface{}) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	data, err := json.Marshal(expected)
+	if err != nil {
+		t.Errorf("invalid expected value: %v", err)
+		return false
+	}
+	want, _ := normalizeJSON(data)
+	var body interface{}
+	err = json.Unmarshal(resp.RawBody, &body)
+	if err != nil {
+		t.Errorf("invalid json body: %v\n%s", err, bodySnippet(resp))
+		return false
+	}
+
+	got, ok := lookupPath(body, strings.Split(path, "."))
+	if !ok {
+		t.Errorf("json path %s not found in\n%s", path, indentJSON(body))
+		return false
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json path %s not equal:\n%s", path, Diff(indentJSON(want), indentJSON(got)))
+		return false
+	}
+	return true
+}
+
+// AssertGolden asserts that the body equals the content of the golden file.
+// Running the tests with -requesttest.update writes the body to the file
+// instead.
+func AssertGolden(t testing.TB, resp *request.Response, filename string) bool {
+	t.Helper()
+
+	if !checkResponse(t, resp) {
+		return false
+	}
+	if *update {
+		err := os.MkdirAll(filepath.Dir(filename), 0o755)
+		if err == nil {
+			err = os.WriteFile(filename, resp.RawBody, 0o644)
+		}
+		if err != nil {
+			t.Errorf("update golden file %s error %v", filename, err)
+			return false
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("read golden file %s error %v, run with -requesttest.update to create it", filename, err)
+		return false
+	}
+	if !bytes.Equal(resp.RawBody, want) {
+		t.Errorf("body not equal to golden file %s:\n%s", filename, Diff(string(want), string(resp.RawBody)))
+		return false
+	}
+	return true
+}
+
+func checkResponse(t testing.TB, resp *request.Response) bool {
+	t.Helper()
+
+	if resp == nil {
+		t.Errorf("response is nil")
+		return false
+	}
+	return true
+}
+
+const maxBodySnippet = 512
+
+func bodySnippet(resp *request.Response) string {
+	if len(resp.RawBody) > maxBodySnippet {
+		return fmt.Sprintf("body: %s...(%d bytes truncated)", resp.RawBody[:maxBodySnippet], len(resp.RawBody)-maxBodySnippet)
+	}
+	return fmt.Sprintf("body: %s", resp.RawBody)
+}
+
+func normalizeJSON(v interface{}) (normalized interface{}, err error) {
+	var data []byte
+	switch v := v.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		data, err = json.Marshal(v)
+		if err != nil {
+			return
+		}
+	}
+
+	err = json.Unmarshal(data, &normalized)
+	return
+}
+
+func indentJSON(v interface{}) string {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return string(data)
+}
+
+func deletePath(v interface{}, keys []string) interface{} {
+	if len(keys) == 0 {
+		return v
+	}
+	key, last := keys[0], len(keys) == 1
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if key != "*" && key != k {
+				continue
+			}
+			if last {
+				delete(v, k)
+			} else {
+				v[k] = deletePath(child, keys[1:])
+			}
+		}
+		return v
+	case []interface{}:
+		if key == "*" {
+			if last {
+				return []interface{}{}
+			}
+			for i := range v {
+				v[i] = deletePath(v[i], keys[1:])
+			}
+			return v
+		}
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(v) {
+			return v
+		}
+		if last {
+			return append(v[:i:i], v[i+1:]...)
+		}
+		v[i] = deletePath(v[i], keys[1:])
+		return v
+	default:
+		return v
+	}
+}
+
+func lookupPath(v interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			child, ok := value[key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, false
+			}
+			v = value[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,205 @@
+package requesttest
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amuwall/go-request"
+)
+
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func newTestResponse() *request.Response {
+	return &request.Response{
+		StatusCode: http.StatusCreated,
+		Header: http.Header{
+			"Content-Type": []string{"application/json; charset=utf-8"},
+			"Location":     []string{"/users/1"},
+		},
+		RawBody: []byte(`{"id":1,"name":"amu","created":"2024-01-01","tags":[{"name":"a","id":7},{"name":"b","id":8}]}`),
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		name       string
+		assert     func(t testing.TB, resp *request.Response) bool
+		want       bool
+		wantErrors string
+	}{
+		{
+			name: "status equals",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertStatus(t, resp, http.StatusCreated)
+			},
+			want: true,
+		},
+		{
+			name: "status not equals",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertStatus(t, resp, http.StatusOK)
+			},
+			want:       false,
+			wantErrors: "status code = 201, want 200",
+		},
+		{
+			name: "status class",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertStatusClass(t, resp, 2)
+			},
+			want: true,
+		},
+		{
+			name: "status not class",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertStatusClass(t, resp, 4)
+			},
+			want:       false,
+			wantErrors: "status code = 201, want 4xx",
+		},
+		{
+			name: "nil response",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertStatus(t, nil, http.StatusOK)
+			},
+			want:       false,
+			wantErrors: "response is nil",
+		},
+		{
+			name: "header present",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertHeader(t, resp, "location")
+			},
+			want: true,
+		},
+		{
+			name: "header missing",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertHeader(t, resp, "ETag")
+			},
+			want:       false,
+			wantErrors: "header ETag is missing",
+		},
+		{
+			name: "header matches",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertHeaderMatches(t, resp, "Location", `^/users/\d+$`)
+			},
+			want: true,
+		},
+		{
+			name: "header not matches",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertHeaderMatches(t, resp, "Location", `^/orders/`)
+			},
+			want:       false,
+			wantErrors: `header Location = ["/users/1"], want match of ^/orders/`,
+		},
+		{
+			name: "json equal in any key order",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertJSONEqual(
+					t, resp,
+					`{"name":"amu","id":1,"created":"2024-01-01","tags":[{"id":7,"name":"a"},{"id":8,"name":"b"}]}`,
+				)
+			},
+			want: true,
+		},
+		{
+			name: "json equal with ignored paths",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertJSONEqual(
+					t, resp,
+					map[string]interface{}{"name": "amu", "tags": []map[string]string{{"name": "a"}, {"name": "b"}}},
+					"id", "created", "tags.*.id",
+				)
+			},
+			want: true,
+		},
+		{
+			name: "json not equal",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertJSONEqual(t, resp, map[string]interface{}{"id": 1, "name": "wall"}, "created", "tags")
+			},
+			want: false,
+			wantErrors: "json body not equal:\n--- want\n+++ got\n" +
+				"  {\n    \"id\": 1,\n-   \"name\": \"wall\"\n+   \"name\": \"amu\"\n  }\n",
+		},
+		{
+			name: "json path equals",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertJSONPath(t, resp, "tags.1", map[string]interface{}{"id": 8, "name": "b"})
+			},
+			want: true,
+		},
+		{
+			name: "json path not equals",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertJSONPath(t, resp, "tags.0.name", "b")
+			},
+			want:       false,
+			wantErrors: "json path tags.0.name not equal:\n--- want\n+++ got\n- \"b\"\n+ \"a\"\n",
+		},
+		{
+			name: "json path not found",
+			assert: func(t testing.TB, resp *request.Response) bool {
+				return AssertJSONPath(t, resp, "tags.2.name", "c")
+			},
+			want:       false,
+			wantErrors: "json path tags.2.name not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeT{TB: t}
+			if got := tt.assert(fake, newTestResponse()); got != tt.want {
+				t.Errorf("assert() = %v, want %v", got, tt.want)
+			}
+			if errors := strings.Join(fake.errors, "|"); !strings.HasPrefix(errors, tt.wantErrors) || (errors == "") != (tt.wantErrors == "") {
+				t.Errorf("assert() errors = %q, want %q", errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testdata", "user.golden")
+	resp := newTestResponse()
+
+	fake := &fakeT{TB: t}
+	if AssertGolden(fake, resp, filename) || len(fake.errors) != 1 {
+		t.Errorf("AssertGolden() with missing file errors = %q", fake.errors)
+	}
+
+	*update = true
+	fake = &fakeT{TB: t}
+	ok := AssertGolden(fake, resp, filename)
+	*update = false
+	if !ok || len(fake.errors) != 0 {
+		t.Fatalf("AssertGolden() with update errors = %q", fake.errors)
+	}
+
+	fake = &fakeT{TB: t}
+	if !AssertGolden(fake, resp, filename) || len(fake.errors) != 0 {
+		t.Errorf("AssertGolden() errors = %q", fake.errors)
+	}
+
+	_ = os.WriteFile(filename, []byte(`{"id":2}`), 0o644)
+	fake = &fakeT{TB: t}
+	if AssertGolden(fake, resp, filename) || len(fake.errors) != 1 || !strings.Contains(fake.errors[0], "- {\"id\":2}") {
+		t.Errorf("AssertGolden() with changed file errors = %q", fake.errors)
+	}
+}
@@ -0,0 +1,339 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver yields the endpoints of a client set by WithResolver.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]Endpoint, error)
+}
+
+// ResolverWatcher is implemented by resolvers which know when their endpoints
+// change, changed is called to refresh them before the next interval.
+type ResolverWatcher interface {
+	Watch(ctx context.Context, changed func())
+}
+
+type ResolverOption func(*resolverLoop) error
+
+type resolverLoop struct {
+	resolver Resolver
+	interval time.Duration
+	timeout  time.Duration
+
+	refresh chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+var ErrNoEndpointResolved = errors.New("no endpoint resolved")
+
+const defaultResolverTimeout = 10 * time.Second
+
+// WithResolver gets the endpoints from resolver when the client is created,
+// then refreshes them every interval. An empty result or an error keeps the
+// endpoints in use.
+func WithResolver(resolver Resolver, interval time.Duration, options ...ResolverOption) ClientOption {
+	return func(c *Client) error {
+		if resolver == nil {
+			return errors.New("resolver is nil")
+		}
+		if interval <= 0 {
+			return fmt.Errorf("invalid resolver interval %s", interval)
+		}
+		loop := &resolverLoop{
+			resolver: resolver,
+			interval: interval,
+			timeout:  defaultResolverTimeout,
+			refresh:  make(chan struct{}, 1),
+		}
+		for _, option := range options {
+			err := option(loop)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.endpointPool()
+		c.resolver = loop
+		return nil
+	}
+}
+
+// WithResolverTimeout bounds every resolve, including the one made when the
+// client is created. The default is 10 seconds.
+func WithResolverTimeout(timeout time.Duration) ResolverOption {
+	return func(l *resolverLoop) error {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid resolver timeout %s", timeout)
+		}
+		l.timeout = timeout
+		return nil
+	}
+}
+
+func (l *resolverLoop) start(c *Client) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+
+	err := l.resolve(ctx, c.pool)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if watcher, ok := l.resolver.(ResolverWatcher); ok {
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			watcher.Watch(ctx, func() {
+				select {
+				case l.refresh <- struct{}{}:
+				default:
+				}
+			})
+		}()
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-l.refresh:
+			}
+			_ = l.resolve(ctx, c.pool)
+		}
+	}()
+
+	return nil
+}
+
+func (l *resolverLoop) stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.wg.Wait()
+}
+
+func (l *resolverLoop) resolve(ctx context.Context, pool *endpointPool) error {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	endpoints, err := l.resolver.Resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("resolve endpoints error %w", err)
+	}
+	if len(endpoints) == 0 {
+		return ErrNoEndpointResolved
+	}
+
+	pool.setEndpoints(endpoints)
+	return nil
+}
+
+type StaticResolver struct {
+	endpoints []Endpoint
+}
+
+func NewStaticResolver(endpoints ...Endpoint) *StaticResolver {
+	return &StaticResolver{
+		endpoints: endpoints,
+	}
+}
+
+func (r *StaticResolver) Resolve(_ context.Context) ([]Endpoint, error) {
+	return r.endpoints, nil
+}
+
+// DNSResolver resolves the A and AAAA records of a host.
+type DNSResolver struct {
+	host string
+	port uint16
+
+	lookupIPAddr func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+func NewDNSResolver(host string, port uint16) *DNSResolver {
+	return &DNSResolver{
+		host: host,
+		port: port,
+
+		lookupIPAddr: net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+func (r *DNSResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	addrs, err := r.lookupIPAddr(ctx, r.host)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]Endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, Endpoint{Host: addr.IP.String(), Port: r.port})
+	}
+	return endpoints, nil
+}
+
+// SRVResolver resolves the SRV records of a service, only the records with the
+// lowest priority are used and their weights are kept.
+type SRVResolver struct {
+	service string
+	proto   string
+	name    string
+
+	lookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+func NewSRVResolver(service, proto, name string) *SRVResolver {
+	return &SRVResolver{
+		service: service,
+		proto:   proto,
+		name:    name,
+
+		lookupSRV: net.DefaultResolver.LookupSRV,
+	}
+}
+
+func (r *SRVResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	_, records, err := r.lookupSRV(ctx, r.service, r.proto, r.name)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	priority := records[0].Priority
+	for _, record := range records {
+		if record.Priority < priority {
+			priority = record.Priority
+		}
+	}
+
+	var endpoints []Endpoint
+	for _, record := range records {
+		if record.Priority != priority {
+			continue
+		}
+		endpoints = append(endpoints, Endpoint{
+			Host:   strings.TrimSuffix(record.Target, "."),
+			Port:   record.Port,
+			Weight: int(record.Weight),
+		})
+	}
+	sort.Slice(endpoints, func(i, k int) bool {
+		return endpoints[i].Address() < endpoints[k].Address()
+	})
+	return endpoints, nil
+}
+
+// FileResolver reads endpoints from a file, one "host:port [weight]" per line,
+// empty lines and lines starting with # are ignored. The file is watched for
+// changes by polling its modification time.
+type FileResolver struct {
+	filename     string
+	pollInterval time.Duration
+}
+
+const defaultFileResolverPollInterval = time.Second
+
+func NewFileResolver(filename string) *FileResolver {
+	return &FileResolver{
+		filename:     filename,
+		pollInterval: defaultFileResolverPollInterval,
+	}
+}
+
+func (r *FileResolver) Resolve(_ context.Context) ([]Endpoint, error) {
+	data, err := os.ReadFile(r.filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseEndpoints(data)
+}
+
+func (r *FileResolver) Watch(ctx context.Context, changed func()) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	lastModTime, lastSize := r.stat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, size := r.stat()
+		if !modTime.Equal(lastModTime) || size != lastSize {
+			lastModTime, lastSize = modTime, size
+			changed()
+		}
+	}
+}
+
+func (r *FileResolver) stat() (time.Time, int64) {
+	info, err := os.Stat(r.filename)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return info.ModTime(), info.Size()
+}
+
+func parseEndpoints(data []byte) ([]Endpoint, error) {
+	var endpoints []Endpoint
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: invalid endpoint %q", line, text)
+		}
+
+		host, port, err := net.SplitHostPort(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		portNumber, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid port %q", line, port)
+		}
+
+		endpoint := Endpoint{Host: host, Port: uint16(portNumber)}
+		if len(fields) == 2 {
+			endpoint.Weight, err = strconv.Atoi(fields[1])
+			if err != nil || endpoint.Weight < 0 {
+				return nil, fmt.Errorf("line %d: invalid weight %q", line, fields[1])
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, scanner.Err()
+}
@@ -0,0 +1,224 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStaticResolver_Resolve(t *testing.T) {
+	endpoints := []Endpoint{{Host: "10.0.0.1", Port: 80}, {Host: "10.0.0.2", Port: 80, Weight: 2}}
+	got, err := NewStaticResolver(endpoints...).Resolve(context.Background())
+	if err != nil {
+		t.Errorf("Resolve() error = %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got, endpoints) {
+		t.Errorf("Resolve() = %v, want %v", got, endpoints)
+	}
+}
+
+func TestDNSResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		addrs   []net.IPAddr
+		err     error
+		want    []Endpoint
+		wantErr bool
+	}{
+		{
+			name:  "resolve a and aaaa records",
+			addrs: []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}, {IP: net.ParseIP("::1")}},
+			want:  []Endpoint{{Host: "10.0.0.1", Port: 8080}, {Host: "::1", Port: 8080}},
+		},
+		{
+			name:    "resolve error",
+			err:     errors.New("no such host"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewDNSResolver("api.example.com", 8080)
+			r.lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+				return tt.addrs, tt.err
+			}
+			got, err := r.Resolve(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSRVResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*net.SRV
+		want    []Endpoint
+	}{
+		{
+			name: "resolve srv records with lowest priority",
+			records: []*net.SRV{
+				{Target: "b.example.com.", Port: 8080, Priority: 10, Weight: 20},
+				{Target: "a.example.com.", Port: 8080, Priority: 10, Weight: 60},
+				{Target: "backup.example.com.", Port: 8080, Priority: 20, Weight: 100},
+			},
+			want: []Endpoint{
+				{Host: "a.example.com", Port: 8080, Weight: 60},
+				{Host: "b.example.com", Port: 8080, Weight: 20},
+			},
+		},
+		{
+			name:    "resolve without srv records",
+			records: nil,
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSRVResolver("http", "tcp", "example.com")
+			r.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				return "", tt.records, nil
+			}
+			got, err := r.Resolve(context.Background())
+			if err != nil {
+				t.Errorf("Resolve() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Endpoint
+		wantErr bool
+	}{
+		{
+			name: "parse endpoints",
+			data: "# backends\n10.0.0.1:80\n\n  10.0.0.2:8080 3\n[::1]:80\n",
+			want: []Endpoint{
+				{Host: "10.0.0.1", Port: 80},
+				{Host: "10.0.0.2", Port: 8080, Weight: 3},
+				{Host: "::1", Port: 80},
+			},
+		},
+		{name: "parse endpoint without port", data: "10.0.0.1\n", wantErr: true},
+		{name: "parse invalid port", data: "10.0.0.1:http\n", wantErr: true},
+		{name: "parse invalid weight", data: "10.0.0.1:80 -1\n", wantErr: true},
+		{name: "parse too many fields", data: "10.0.0.1:80 1 2\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEndpoints([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseEndpoints() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEndpoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithResolver(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Server", name)
+		}))
+	}
+	serverA, serverB := newServer("a"), newServer("b")
+	defer serverA.Close()
+	defer serverB.Close()
+
+	filename := filepath.Join(t.TempDir(), "endpoints")
+	_ = os.WriteFile(filename, []byte(newTestEndpoint(serverA, 1).Address()+"\n"), 0o644)
+
+	resolver := NewFileResolver(filename)
+	resolver.pollInterval = 5 * time.Millisecond
+	client, err := NewClient("api.example.com", WithScheme("http"), WithResolver(resolver, time.Hour))
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+	defer client.Close()
+
+	server := func() string {
+		req, _ := NewRequest(http.MethodGet, "/api/test")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err.Error()
+		}
+		return resp.Header.Get("X-Server")
+	}
+
+	if got := server(); got != "a" {
+		t.Errorf("Do() server = %v, want %v", got, "a")
+		return
+	}
+
+	_ = os.WriteFile(filename, []byte("# moved\n"+newTestEndpoint(serverB, 1).Address()+"\n"), 0o644)
+	if !waitFor(t, func() bool { return server() == "b" }) {
+		t.Errorf("Do() server = %v, want %v", server(), "b")
+	}
+}
+
+type hangingResolver struct{}
+
+func (hangingResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestWithResolver_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ClientOption
+	}{
+		{name: "nil resolver", options: []ClientOption{WithResolver(nil, time.Second)}},
+		{name: "invalid interval", options: []ClientOption{WithResolver(NewStaticResolver(), 0)}},
+		{name: "no endpoint resolved", options: []ClientOption{WithResolver(NewStaticResolver(), time.Second)}},
+		{
+			name:    "resolve error",
+			options: []ClientOption{WithResolver(NewFileResolver("/not/exist"), time.Second)},
+		},
+		{
+			name: "invalid timeout",
+			options: []ClientOption{
+				WithResolver(NewStaticResolver(Endpoint{Host: "a", Port: 80}), time.Second, WithResolverTimeout(0)),
+			},
+		},
+		{
+			name: "resolve timeout",
+			options: []ClientOption{
+				WithResolver(hangingResolver{}, time.Second, WithResolverTimeout(10*time.Millisecond)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient("127.0.0.1", tt.options...)
+			if err == nil {
+				t.Errorf("NewClient() error = %v, wantErr true", err)
+			}
+		})
+	}
+}
@@ -6,12 +6,20 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type Response struct {
 	StatusCode int
 	Header     http.Header
 	RawBody    []byte
+
+	Redirects []Redirect
+
+	// CacheStatus is empty unless the client has a cache.
+	CacheStatus CacheStatus
+
+	Timings Timings
 }
 
 func parseResponse(httpResponse *http.Response) (response *Response, err error) {
@@ -26,14 +34,47 @@ func parseResponse(httpResponse *http.Response) (response *Response, err error)
 		StatusCode: httpResponse.StatusCode,
 		Header:     httpResponse.Header,
 		RawBody:    rawBody,
+
+		Redirects: parseRedirects(httpResponse),
 	}
 
 	return
 }
 
+func (resp *Response) clone() *Response {
+	if resp == nil {
+		return nil
+	}
+
+	clone := *resp
+	clone.Header = resp.Header.Clone()
+	clone.RawBody = append([]byte(nil), resp.RawBody...)
+	clone.Redirects = append([]Redirect(nil), resp.Redirects...)
+
+	return &clone
+}
+
 func (resp *Response) UnmarshalJSONBody(val interface{}) (err error) {
 	if !strings.Contains(resp.Header.Get(contentTypeHeader), contentTypeJson) {
 		return fmt.Errorf("response content-type not json, it is %s", resp.Header.Get("Content-Type"))
 	}
 	return json.Unmarshal(resp.RawBody, val)
 }
+
+func (resp *Response) Cookies() []*http.Cookie {
+	return (&http.Response{Header: resp.Header}).Cookies()
+}
+
+func (resp *Response) ETag() string {
+	return resp.Header.Get(etagHeader)
+}
+
+// LastModified returns the zero time when the Last-Modified header is missing
+// or invalid.
+func (resp *Response) LastModified() time.Time {
+	lastModified, err := http.ParseTime(resp.Header.Get(lastModifiedHeader))
+	if err != nil {
+		return time.Time{}
+	}
+	return lastModified
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test_parseResponse(t *testing.T) {
@@ -101,3 +102,73 @@ func TestResponse_UnmarshalJSONBody(t *testing.T) {
 		})
 	}
 }
+
+func TestResponse_Cookies(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   []string
+	}{
+		{
+			name:   "response cookies",
+			header: http.Header{"Set-Cookie": {"session=token; Path=/", "lang=en"}},
+			want:   []string{"session", "lang"},
+		},
+		{
+			name:   "response without cookies",
+			header: http.Header{},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{Header: tt.header}
+			var got []string
+			for _, cookie := range resp.Cookies() {
+				got = append(got, cookie.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cookies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponse_ETag(t *testing.T) {
+	resp := &Response{Header: http.Header{"Etag": {`W/"v1"`}}}
+	if got := resp.ETag(); got != `W/"v1"` {
+		t.Errorf("ETag() = %v, want %v", got, `W/"v1"`)
+	}
+}
+
+func TestResponse_LastModified(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Time
+	}{
+		{
+			name:   "last modified",
+			header: http.Header{"Last-Modified": {"Mon, 01 Jan 2024 19:04:05 GMT"}},
+			want:   time.Date(2024, 1, 1, 19, 4, 5, 0, time.UTC),
+		},
+		{
+			name:   "invalid last modified",
+			header: http.Header{"Last-Modified": {"yesterday"}},
+			want:   time.Time{},
+		},
+		{
+			name:   "without last modified",
+			header: http.Header{},
+			want:   time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{Header: tt.header}
+			if got := resp.LastModified(); !got.Equal(tt.want) {
+				t.Errorf("LastModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package request
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Signer interface {
+	Sign(httpRequest *http.Request) error
+}
+
+type SignatureHash string
+
+const (
+	SignatureHashSHA1   SignatureHash = "sha1"
+	SignatureHashSHA256 SignatureHash = "sha256"
+	SignatureHashSHA512 SignatureHash = "sha512"
+)
+
+type SignatureEncoding string
+
+const (
+	SignatureEncodingHex    SignatureEncoding = "hex"
+	SignatureEncodingBase64 SignatureEncoding = "base64"
+)
+
+type SignatureComponent string
+
+const (
+	SignMethod    SignatureComponent = "method"
+	SignPath      SignatureComponent = "path"
+	SignQuery     SignatureComponent = "query"
+	SignTimestamp SignatureComponent = "timestamp"
+	SignBody      SignatureComponent = "body"
+
+	signHeaderPrefix = "header:"
+)
+
+func SignHeader(name string) SignatureComponent {
+	return SignatureComponent(signHeaderPrefix + http.CanonicalHeaderKey(name))
+}
+
+const (
+	signatureFormatTimestamp = "{timestamp}"
+	signatureFormatSignature = "{signature}"
+)
+
+var (
+	ErrSignatureMissing  = errors.New("signature missing")
+	ErrSignatureMismatch = errors.New("signature mismatch")
+	ErrSignatureExpired  = errors.New("signature timestamp out of tolerance")
+)
+
+type HMACSigner struct {
+	key        []byte
+	hash       SignatureHash
+	encoding   SignatureEncoding
+	components []SignatureComponent
+	separator  string
+
+	header          string
+	format          string
+	timestampHeader string
+
+	now func() time.Time
+}
+
+type HMACSignerOption func(*HMACSigner) error
+
+const (
+	defaultSignatureHeader    = "X-Signature"
+	defaultTimestampHeader    = "X-Signature-Timestamp"
+	defaultSignatureFormat    = signatureFormatSignature
+	defaultSignatureSeparator = "\n"
+)
+
+func NewHMACSigner(key []byte, options ...HMACSignerOption) (signer *HMACSigner, err error) {
+	signer = &HMACSigner{
+		key:        key,
+		hash:       SignatureHashSHA256,
+		encoding:   SignatureEncodingHex,
+		components: []SignatureComponent{SignMethod, SignPath, SignTimestamp, SignBody},
+		separator:  defaultSignatureSeparator,
+
+		header:          defaultSignatureHeader,
+		format:          defaultSignatureFormat,
+		timestampHeader: defaultTimestampHeader,
+
+		now: time.Now,
+	}
+
+	for _, option := range options {
+		err = option(signer)
+		if err != nil {
+			return
+		}
+	}
+
+	if signer.signsTimestamp() && !strings.Contains(signer.format, signatureFormatTimestamp) && signer.timestampHeader == "" {
+		err = errors.New("signed timestamp is sent neither in the signature format nor in a timestamp header")
+		return
+	}
+
+	return
+}
+
+func (s *HMACSigner) signsTimestamp() bool {
+	for _, component := range s.components {
+		if component == SignTimestamp {
+			return true
+		}
+	}
+	return false
+}
+
+func WithSignatureHash(signatureHash SignatureHash) HMACSignerOption {
+	return func(s *HMACSigner) error {
+		if newSignatureHash(signatureHash) == nil {
+			return fmt.Errorf("unsupported signature hash %s", signatureHash)
+		}
+		s.hash = signatureHash
+		return nil
+	}
+}
+
+func WithSignatureEncoding(encoding SignatureEncoding) HMACSignerOption {
+	return func(s *HMACSigner) error {
+		if encoding != SignatureEncodingHex && encoding != SignatureEncodingBase64 {
+			return fmt.Errorf("unsupported signature encoding %s", encoding)
+		}
+		s.encoding = encoding
+		return nil
+	}
+}
+
+func WithSignedComponents(separator string, components ...SignatureComponent) HMACSignerOption {
+	return func(s *HMACSigner) error {
+		if len(components) == 0 {
+			return errors.New("signed components is empty")
+		}
+		s.separator = separator
+		s.components = components
+		return nil
+	}
+}
+
+// WithSignatureHeader sets the header carrying the signature. The format may
+// reference {signature} and {timestamp}, e.g. "t={timestamp},v1={signature}".
+func WithSignatureHeader(header, format string) HMACSignerOption {
+	return func(s *HMACSigner) error {
+		if !strings.Contains(format, signatureFormatSignature) {
+			return fmt.Errorf("signature format %q does not contain %s", format, signatureFormatSignature)
+		}
+		s.header = header
+		s.format = format
+		return nil
+	}
+}
+
+// WithSignatureTimestampHeader sets the header carrying the signed timestamp,
+// X-Signature-Timestamp by default. An empty header sends no timestamp header.
+func WithSignatureTimestampHeader(header string) HMACSignerOption {
+	return func(s *HMACSigner) error {
+		s.timestampHeader = header
+		return nil
+	}
+}
+
+func (s *HMACSigner) Sign(httpRequest *http.Request) error {
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+
+	signature, err := s.signature(httpRequest, timestamp)
+	if err != nil {
+		return err
+	}
+
+	value := strings.ReplaceAll(s.format, signatureFormatTimestamp, timestamp)
+	value = strings.ReplaceAll(value, signatureFormatSignature, signature)
+	httpRequest.Header.Set(s.header, value)
+
+	if s.timestampHeader != "" {
+		httpRequest.Header.Set(s.timestampHeader, timestamp)
+	}
+
+	return nil
+}
+
+// VerifySignature checks the signature of a received request. A zero tolerance
+// disables the timestamp freshness check.
+func (s *HMACSigner) VerifySignature(httpRequest *http.Request, tolerance time.Duration) error {
+	value := httpRequest.Header.Get(s.header)
+	if value == "" {
+		return ErrSignatureMissing
+	}
+
+	timestamp, signature, err := s.parseHeader(value)
+	if err != nil {
+		return err
+	}
+	if timestamp == "" && s.timestampHeader != "" {
+		timestamp = httpRequest.Header.Get(s.timestampHeader)
+	}
+
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse signature timestamp error %w", err)
+		}
+		diff := s.now().Sub(time.Unix(unix, 0))
+		if diff > tolerance || diff < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	expected, err := s.signature(httpRequest, timestamp)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
+
+func (s *HMACSigner) parseHeader(value string) (timestamp, signature string, err error) {
+	pattern := regexp.QuoteMeta(s.format)
+	pattern = strings.Replace(
+		pattern, regexp.QuoteMeta(signatureFormatTimestamp), `(?P<timestamp>[0-9]+)`, 1,
+	)
+	pattern = strings.Replace(
+		pattern, regexp.QuoteMeta(signatureFormatSignature), `(?P<signature>[A-Za-z0-9+/=_-]+)`, 1,
+	)
+
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return "", "", fmt.Errorf("compile signature format error %w", err)
+	}
+
+	match := re.FindStringSubmatch(value)
+	if match == nil {
+		return "", "", fmt.Errorf("signature header %q does not match format %q", value, s.format)
+	}
+
+	if index := re.SubexpIndex("timestamp"); index >= 0 {
+		timestamp = match[index]
+	}
+	signature = match[re.SubexpIndex("signature")]
+
+	return
+}
+
+func (s *HMACSigner) signature(httpRequest *http.Request, timestamp string) (string, error) {
+	message, err := s.canonicalMessage(httpRequest, timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(newSignatureHash(s.hash), s.key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	if s.encoding == SignatureEncodingBase64 {
+		return base64.StdEncoding.EncodeToString(sum), nil
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+func (s *HMACSigner) canonicalMessage(httpRequest *http.Request, timestamp string) ([]byte, error) {
+	parts := make([][]byte, 0, len(s.components))
+	for _, component := range s.components {
+		switch component {
+		case SignMethod:
+			parts = append(parts, []byte(strings.ToUpper(httpRequest.Method)))
+		case SignPath:
+			parts = append(parts, []byte(httpRequest.URL.EscapedPath()))
+		case SignQuery:
+			parts = append(parts, []byte(httpRequest.URL.RawQuery))
+		case SignTimestamp:
+			parts = append(parts, []byte(timestamp))
+		case SignBody:
+			body, err := readRequestBody(httpRequest)
+			if err != nil {
+				return nil, fmt.Errorf("read body for signature error %w", err)
+			}
+			parts = append(parts, body)
+		default:
+			name, ok := strings.CutPrefix(string(component), signHeaderPrefix)
+			if !ok {
+				return nil, fmt.Errorf("unsupported signature component %s", component)
+			}
+			parts = append(parts, []byte(httpRequest.Header.Get(name)))
+		}
+	}
+
+	return bytes.Join(parts, []byte(s.separator)), nil
+}
+
+// readRequestBody returns the body and leaves the request readable again.
+func readRequestBody(httpRequest *http.Request) ([]byte, error) {
+	if httpRequest.Body == nil || httpRequest.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if httpRequest.GetBody != nil {
+		body, err := httpRequest.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(httpRequest.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = httpRequest.Body.Close()
+
+	httpRequest.Body = io.NopCloser(bytes.NewReader(data))
+	httpRequest.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return data, nil
+}
+
+func newSignatureHash(signatureHash SignatureHash) func() hash.Hash {
+	switch signatureHash {
+	case SignatureHashSHA1:
+		return sha1.New
+	case SignatureHashSHA256:
+		return sha256.New
+	case SignatureHashSHA512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
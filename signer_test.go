@@ -0,0 +1,274 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testSignature(key, message string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestNewHMACSigner(t *testing.T) {
+	type args struct {
+		options []HMACSignerOption
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "new signer",
+			args:    args{options: nil},
+			wantErr: false,
+		},
+		{
+			name:    "new signer with unsupported hash",
+			args:    args{options: []HMACSignerOption{WithSignatureHash("md5")}},
+			wantErr: true,
+		},
+		{
+			name:    "new signer with format without signature",
+			args:    args{options: []HMACSignerOption{WithSignatureHeader("X-Signature", "t={timestamp}")}},
+			wantErr: true,
+		},
+		{
+			name:    "new signer with signed timestamp not sent",
+			args:    args{options: []HMACSignerOption{WithSignatureTimestampHeader("")}},
+			wantErr: true,
+		},
+		{
+			name: "new signer with signed timestamp in format",
+			args: args{options: []HMACSignerOption{
+				WithSignatureTimestampHeader(""),
+				WithSignatureHeader("X-Signature", "t={timestamp},v1={signature}"),
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "new signer without components",
+			args:    args{options: []HMACSignerOption{WithSignedComponents(".")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHMACSigner([]byte("secret"), tt.args.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewHMACSigner() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHMACSigner_Sign(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		options    []HMACSignerOption
+		header     string
+		wantHeader string
+	}{
+		{
+			name:       "sign with default components",
+			options:    nil,
+			header:     "X-Signature",
+			wantHeader: testSignature("secret", "POST\n/api/test\n1700000000\n{\"hello\":\"world\"}"),
+		},
+		{
+			name: "sign stripe style",
+			options: []HMACSignerOption{
+				WithSignedComponents(".", SignTimestamp, SignBody),
+				WithSignatureHeader("Stripe-Signature", "t={timestamp},v1={signature}"),
+			},
+			header:     "Stripe-Signature",
+			wantHeader: "t=1700000000,v1=" + testSignature("secret", "1700000000.{\"hello\":\"world\"}"),
+		},
+		{
+			name: "sign github style",
+			options: []HMACSignerOption{
+				WithSignedComponents("", SignBody),
+				WithSignatureHeader("X-Hub-Signature-256", "sha256={signature}"),
+			},
+			header:     "X-Hub-Signature-256",
+			wantHeader: "sha256=" + testSignature("secret", "{\"hello\":\"world\"}"),
+		},
+		{
+			name: "sign with query and header",
+			options: []HMACSignerOption{
+				WithSignedComponents("|", SignMethod, SignQuery, SignHeader("x-tenant")),
+			},
+			header:     "X-Signature",
+			wantHeader: testSignature("secret", "POST|test=value|acme"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewHMACSigner([]byte("secret"), tt.options...)
+			if err != nil {
+				t.Errorf("NewHMACSigner() error = %v", err)
+				return
+			}
+			signer.now = func() time.Time { return now }
+
+			req, _ := NewRequest(
+				http.MethodPost,
+				"/api/test",
+				WithHeaders(map[string]string{"X-Tenant": "acme"}),
+				WithQueryParams(NewQueryParams(map[string]string{"test": "value"})),
+				WithBodyParams(NewJsonBodyParams(map[string]string{"hello": "world"})),
+			)
+			httpRequest, _ := req.build("https://127.0.0.1")
+
+			err = signer.Sign(httpRequest)
+			if err != nil {
+				t.Errorf("Sign() error = %v", err)
+				return
+			}
+			if got := httpRequest.Header.Get(tt.header); got != tt.wantHeader {
+				t.Errorf("Sign() header = %v, want %v", got, tt.wantHeader)
+			}
+			if req.Headers.Get(tt.header) != "" {
+				t.Errorf("Sign() modified request headers %v", req.Headers)
+			}
+		})
+	}
+}
+
+func TestHMACSigner_VerifySignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name      string
+		tamper    func(r *http.Request)
+		verifyAt  time.Time
+		tolerance time.Duration
+		wantErr   error
+	}{
+		{
+			name:      "verify signature",
+			tamper:    func(r *http.Request) {},
+			verifyAt:  now.Add(time.Minute),
+			tolerance: 5 * time.Minute,
+			wantErr:   nil,
+		},
+		{
+			name: "verify tampered body",
+			tamper: func(r *http.Request) {
+				r.Body = http.NoBody
+				r.GetBody = nil
+			},
+			verifyAt: now,
+			wantErr:  ErrSignatureMismatch,
+		},
+		{
+			name:      "verify expired signature",
+			tamper:    func(r *http.Request) {},
+			verifyAt:  now.Add(time.Hour),
+			tolerance: 5 * time.Minute,
+			wantErr:   ErrSignatureExpired,
+		},
+		{
+			name: "verify missing signature",
+			tamper: func(r *http.Request) {
+				r.Header.Del("Stripe-Signature")
+			},
+			verifyAt: now,
+			wantErr:  ErrSignatureMissing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, _ := NewHMACSigner(
+				[]byte("secret"),
+				WithSignatureHash(SignatureHashSHA512),
+				WithSignatureEncoding(SignatureEncodingBase64),
+				WithSignedComponents(".", SignTimestamp, SignMethod, SignPath, SignBody),
+				WithSignatureHeader("Stripe-Signature", "t={timestamp},v1={signature}"),
+			)
+			signer.now = func() time.Time { return now }
+
+			req, _ := NewRequest(
+				http.MethodPost,
+				"/api/test",
+				WithBodyParams(NewJsonBodyParams(map[string]string{"hello": "world"})),
+			)
+			httpRequest, _ := req.build("https://127.0.0.1")
+			_ = signer.Sign(httpRequest)
+
+			tt.tamper(httpRequest)
+			signer.now = func() time.Time { return tt.verifyAt }
+
+			err := signer.VerifySignature(httpRequest, tt.tolerance)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHMACSigner_VerifySignature_defaults(t *testing.T) {
+	signer, err := NewHMACSigner([]byte("secret"))
+	if err != nil {
+		t.Fatalf("NewHMACSigner() error = %v", err)
+	}
+
+	req, _ := NewRequest(
+		http.MethodPost,
+		"/api/test",
+		WithBodyParams(NewJsonBodyParams(map[string]string{"hello": "world"})),
+	)
+	httpRequest, _ := req.build("https://127.0.0.1")
+	err = signer.Sign(httpRequest)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if httpRequest.Header.Get(defaultTimestampHeader) == "" {
+		t.Errorf("Sign() timestamp header missing %v", httpRequest.Header)
+	}
+
+	err = signer.VerifySignature(httpRequest, time.Minute)
+	if err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+}
+
+func TestWithSigner(t *testing.T) {
+	signer, _ := NewHMACSigner(
+		[]byte("secret"),
+		WithSignatureTimestampHeader("X-Timestamp"),
+	)
+
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifyErr = signer.VerifySignature(r, time.Minute)
+		if r.Header.Get("X-Timestamp") == "" {
+			verifyErr = errors.New("timestamp header missing")
+		}
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server, WithSigner(signer))
+	req, _ := NewRequest(
+		http.MethodPut,
+		"/api/test",
+		WithBodyParams(NewJsonBodyParams(map[string]string{"hello": "world"})),
+	)
+
+	_, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+	if verifyErr != nil {
+		t.Errorf("VerifySignature() error = %v", verifyErr)
+	}
+}
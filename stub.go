@@ -0,0 +1,253 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StubServerOption func(*StubServer) error
+
+// StubServer serves recorded interactions to stand in for the server they
+// were recorded from. Requests that match no interaction are answered with
+// 501 Not Implemented and a diff against the closest one.
+type StubServer struct {
+	*httptest.Server
+
+	interactions []*CassetteInteraction
+	matchers     []CassetteMatcher
+	latency      time.Duration
+
+	mu        sync.Mutex
+	used      []bool
+	unmatched []*StubMismatch
+	overused  []CassetteRequest
+}
+
+// StubMismatch is a request that matched no interaction.
+type StubMismatch struct {
+	Request CassetteRequest
+	Closest *CassetteInteraction
+	Diff    string
+}
+
+// NewStubServer starts a stub server, requests are matched by method, URL
+// and query by default. Recorded URLs are matched without scheme and host.
+func NewStubServer(options ...StubServerOption) (server *StubServer, err error) {
+	server = &StubServer{
+		matchers: []CassetteMatcher{MatchMethod, MatchURL, MatchQuery},
+	}
+
+	for _, option := range options {
+		err = option(server)
+		if err != nil {
+			return
+		}
+	}
+
+	server.used = make([]bool, len(server.interactions))
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+
+	return
+}
+
+func WithStubInteractions(interactions ...*CassetteInteraction) StubServerOption {
+	return func(s *StubServer) error {
+		for _, interaction := range interactions {
+			s.interactions = append(s.interactions, relativeInteraction(interaction))
+		}
+		return nil
+	}
+}
+
+// WithStubCassette serves the interactions of a cassette file.
+func WithStubCassette(filename string) StubServerOption {
+	return func(s *StubServer) error {
+		interactions, err := LoadCassette(filename)
+		if err != nil {
+			return err
+		}
+		return WithStubInteractions(interactions...)(s)
+	}
+}
+
+func WithStubMatchers(matchers ...CassetteMatcher) StubServerOption {
+	return func(s *StubServer) error {
+		s.matchers = matchers
+		return nil
+	}
+}
+
+// WithStubLatency delays every response by latency.
+func WithStubLatency(latency time.Duration) StubServerOption {
+	return func(s *StubServer) error {
+		if latency < 0 {
+			return fmt.Errorf("invalid stub latency %v", latency)
+		}
+		s.latency = latency
+		return nil
+	}
+}
+
+// Unmatched returns the requests that matched no interaction.
+func (s *StubServer) Unmatched() []*StubMismatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*StubMismatch{}, s.unmatched...)
+}
+
+// Overused returns the requests that were answered with the last matching
+// interaction again because all matching interactions were already used.
+func (s *StubServer) Overused() []CassetteRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]CassetteRequest{}, s.overused...)
+}
+
+// relativeInteraction copies interaction with its URL stripped of scheme and
+// host.
+func relativeInteraction(interaction *CassetteInteraction) *CassetteInteraction {
+	relative := *interaction
+	if u, err := url.Parse(interaction.Request.URL); err == nil {
+		relative.Request.URL = u.RequestURI()
+	}
+	return &relative
+}
+
+func (s *StubServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := readRequestBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	request := CassetteRequest{
+		Method:  r.Method,
+		URL:     r.URL.RequestURI(),
+		Headers: r.Header.Clone(),
+	}
+	request.Body, request.BodyEncoding = encodeCassetteBody(body)
+
+	interaction := s.find(&request)
+	if interaction == nil {
+		mismatch := s.mismatch(&request)
+		http.Error(w, mismatch.Diff, http.StatusNotImplemented)
+		return
+	}
+
+	if s.latency > 0 && sleep(r, s.latency) != nil {
+		return
+	}
+
+	responseBody, err := decodeCassetteBody(interaction.Response.Body, interaction.Response.BodyEncoding)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for name, values := range interaction.Response.Headers {
+		if name == "Content-Length" || name == "Transfer-Encoding" {
+			continue
+		}
+		w.Header()[name] = append([]string{}, values...)
+	}
+	w.WriteHeader(interaction.Response.StatusCode)
+	_, _ = w.Write(responseBody)
+}
+
+// find returns the first unused matching interaction, or the last matching
+// one when all of them were used, so that recorded sequences are replayed in
+// order. Requests answered again are recorded as overused.
+func (s *StubServer) find(request *CassetteRequest) *CassetteInteraction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := -1
+	for i, interaction := range s.interactions {
+		if !s.match(request, &interaction.Request) {
+			continue
+		}
+		if !s.used[i] {
+			s.used[i] = true
+			return interaction
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil
+	}
+	s.overused = append(s.overused, *request)
+	return s.interactions[last]
+}
+
+func (s *StubServer) match(request, recorded *CassetteRequest) bool {
+	for _, matcher := range s.matchers {
+		if !matcher(request, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// mismatch records request with a diff against the interaction matched by
+// the most matchers.
+func (s *StubServer) mismatch(request *CassetteRequest) *StubMismatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mismatch := &StubMismatch{Request: *request}
+	best := -1
+	for _, interaction := range s.interactions {
+		score := 0
+		for _, matcher := range s.matchers {
+			if matcher(request, &interaction.Request) {
+				score++
+			}
+		}
+		if score > best {
+			best, mismatch.Closest = score, interaction
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "no interaction matches %s %s\n", request.Method, request.URL)
+	if mismatch.Closest == nil {
+		b.WriteString("no interactions recorded\n")
+	} else {
+		closest := &mismatch.Closest.Request
+		fmt.Fprintf(&b, "closest interaction %s %s:\n", closest.Method, closest.URL)
+		diffField(&b, "method", closest.Method, request.Method)
+		diffField(&b, "url", closest.URL, request.URL)
+		names := make([]string, 0, len(closest.Headers))
+		for name := range closest.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			diffField(
+				&b, "header "+name,
+				strings.Join(closest.Headers.Values(name), ", "),
+				strings.Join(request.Headers.Values(name), ", "),
+			)
+		}
+		if !MatchBody(request, closest) {
+			diffField(&b, "body", closest.Body, request.Body)
+		}
+	}
+	mismatch.Diff = b.String()
+
+	s.unmatched = append(s.unmatched, mismatch)
+	return mismatch
+}
+
+func diffField(b *strings.Builder, name, recorded, got string) {
+	if recorded == got {
+		return
+	}
+	fmt.Fprintf(b, "- %s: %s\n+ %s: %s\n", name, recorded, name, got)
+}
@@ -0,0 +1,181 @@
+package request
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStubTestInteractions() []*CassetteInteraction {
+	return []*CassetteInteraction{
+		{
+			Request: CassetteRequest{Method: http.MethodGet, URL: "https://api.example.com/users/1?fields=name"},
+			Response: CassetteResponse{
+				StatusCode: http.StatusOK,
+				Headers:    http.Header{contentTypeHeader: []string{contentTypeJson}, "Content-Length": []string{"99"}},
+				Body:       `{"name":"amu"}`,
+			},
+		},
+		{
+			Request:  CassetteRequest{Method: http.MethodGet, URL: "https://api.example.com/jobs/1"},
+			Response: CassetteResponse{StatusCode: http.StatusOK, Body: "running"},
+		},
+		{
+			Request:  CassetteRequest{Method: http.MethodGet, URL: "https://api.example.com/jobs/1"},
+			Response: CassetteResponse{StatusCode: http.StatusOK, Body: "done"},
+		},
+		{
+			Request: CassetteRequest{
+				Method:  http.MethodPost,
+				URL:     "https://api.example.com/users",
+				Headers: http.Header{"X-Version": []string{"2"}},
+				Body:    `{"name":"wall"}`,
+			},
+			Response: CassetteResponse{StatusCode: http.StatusCreated},
+		},
+	}
+}
+
+func TestNewStubServer(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "cassette.json")
+	_ = os.WriteFile(filename, []byte(`{"version":1,"interactions":[{"request":{"method":"GET","url":"http://a/"},"response":{"status_code":200}}]}`), 0o644)
+
+	tests := []struct {
+		name             string
+		options          []StubServerOption
+		wantInteractions int
+		wantErr          bool
+	}{
+		{
+			name:             "new stub server with interactions",
+			options:          []StubServerOption{WithStubInteractions(newStubTestInteractions()...)},
+			wantInteractions: 4,
+		},
+		{
+			name:             "new stub server with cassette",
+			options:          []StubServerOption{WithStubCassette(filename)},
+			wantInteractions: 1,
+		},
+		{
+			name:    "new stub server with missing cassette",
+			options: []StubServerOption{WithStubCassette(filepath.Join(dir, "missing.json"))},
+			wantErr: true,
+		},
+		{
+			name:    "new stub server with invalid latency",
+			options: []StubServerOption{WithStubLatency(-time.Second)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewStubServer(tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStubServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer server.Close()
+			if len(server.interactions) != tt.wantInteractions {
+				t.Errorf("NewStubServer() interactions = %v, want %v", len(server.interactions), tt.wantInteractions)
+			}
+		})
+	}
+}
+
+func TestStubServer(t *testing.T) {
+	server, err := NewStubServer(
+		WithStubInteractions(newStubTestInteractions()...),
+		WithStubMatchers(MatchMethod, MatchURL, MatchQuery, MatchHeaders("X-Version"), MatchBody),
+		WithStubLatency(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("NewStubServer() error = %v", err)
+	}
+	defer server.Close()
+	client, _ := newTestClient(server.Server)
+
+	type want struct {
+		statusCode int
+		body       string
+	}
+	tests := []struct {
+		name    string
+		request func() (*Request, error)
+		want    want
+	}{
+		{
+			name: "serve matched interaction",
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/users/1", WithQueryParams(NewQueryParams(map[string]string{"fields": "name"})))
+			},
+			want: want{statusCode: http.StatusOK, body: `{"name":"amu"}`},
+		},
+		{
+			name: "serve first of sequence",
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/jobs/1")
+			},
+			want: want{statusCode: http.StatusOK, body: "running"},
+		},
+		{
+			name: "serve second of sequence",
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/jobs/1")
+			},
+			want: want{statusCode: http.StatusOK, body: "done"},
+		},
+		{
+			name: "serve last of sequence again",
+			request: func() (*Request, error) {
+				return NewRequest(http.MethodGet, "/jobs/1")
+			},
+			want: want{statusCode: http.StatusOK, body: "done"},
+		},
+		{
+			name: "report unmatched request",
+			request: func() (*Request, error) {
+				return NewRequest(
+					http.MethodPost, "/users",
+					WithHeaders(map[string]string{"X-Version": "3", contentTypeHeader: contentTypeJson}),
+					WithBodyParams(NewJsonBodyParams(map[string]string{"name": "amu"})),
+				)
+			},
+			want: want{
+				statusCode: http.StatusNotImplemented,
+				body: "no interaction matches POST /users\n" +
+					"closest interaction POST /users:\n" +
+					"- header X-Version: 2\n+ header X-Version: 3\n" +
+					"- body: {\"name\":\"wall\"}\n+ body: {\"name\":\"amu\"}\n\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := tt.request()
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if resp.StatusCode != tt.want.statusCode || string(resp.RawBody) != tt.want.body {
+				t.Errorf("Do() = %v %q, want %v %q", resp.StatusCode, resp.RawBody, tt.want.statusCode, tt.want.body)
+			}
+			if resp.Timings.Total < 10*time.Millisecond && resp.StatusCode != http.StatusNotImplemented {
+				t.Errorf("Do() timings = %v, want latency", resp.Timings.Total)
+			}
+		})
+	}
+
+	unmatched := server.Unmatched()
+	if len(unmatched) != 1 || unmatched[0].Closest.Request.URL != "/users" {
+		t.Errorf("Unmatched() = %v", unmatched)
+	}
+	overused := server.Overused()
+	if len(overused) != 1 || overused[0].Method != http.MethodGet || overused[0].URL != "/jobs/1" {
+		t.Errorf("Overused() = %v", overused)
+	}
+}
@@ -0,0 +1,147 @@
+package request
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings of a request, when redirects are followed the phases are those of
+// the last round trip while Total covers all of them.
+type Timings struct {
+	DNSLookup    time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// Wait is the time from the request being written to the first byte of
+	// the response.
+	Wait     time.Duration
+	Transfer time.Duration
+	Total    time.Duration
+
+	ConnectionReused bool
+	RemoteAddr       string
+}
+
+// TimingsHook is called after every round trip of the client, including
+// failed ones and each attempt of a retried or hedged request.
+type TimingsHook func(httpRequest *http.Request, timings Timings, err error)
+
+func WithTimingsHook(hook TimingsHook) ClientOption {
+	return func(c *Client) error {
+		c.timingsHooks = append(c.timingsHooks, hook)
+		return nil
+	}
+}
+
+type timingsTracer struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	timings Timings
+}
+
+func newTimingsTracer() *timingsTracer {
+	return &timingsTracer{start: time.Now()}
+}
+
+func (t *timingsTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// a redirect starts a new round trip
+			t.timings = Timings{}
+			t.connectStart, t.gotConn, t.wroteRequest, t.firstByte = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.DNSLookup = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// the dialer may try several addresses, the first one starts connecting
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.timings.Connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TLSHandshake = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Now()
+			t.timings.ConnectionReused = info.Reused
+			if info.Conn != nil && info.Conn.RemoteAddr() != nil {
+				t.timings.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Now()
+			if !t.wroteRequest.IsZero() {
+				t.timings.Wait = t.firstByte.Sub(t.wroteRequest)
+			}
+		},
+	}
+}
+
+// finish returns the timings once the response body is read.
+func (t *timingsTracer) finish() Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if !t.firstByte.IsZero() {
+		t.timings.Transfer = now.Sub(t.firstByte)
+	}
+	t.timings.Total = now.Sub(t.start)
+	return t.timings
+}
+
+// send returns the time spent writing the request of the last round trip.
+func (t *timingsTracer) send() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.gotConn.IsZero() || !t.wroteRequest.After(t.gotConn) {
+		return 0
+	}
+	return t.wroteRequest.Sub(t.gotConn)
+}
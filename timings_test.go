@@ -0,0 +1,89 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResponse_Timings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var hooked []Timings
+	client, err := newTestClient(
+		server,
+		WithSkipVerifyCertificates(),
+		WithTimingsHook(func(httpRequest *http.Request, timings Timings, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			hooked = append(hooked, timings)
+		}),
+	)
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+
+	tests := []struct {
+		name       string
+		wantReused bool
+	}{
+		{name: "new connection", wantReused: false},
+		{name: "reused connection", wantReused: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := NewRequest(http.MethodGet, "/api/test")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+
+			timings := resp.Timings
+			if timings.ConnectionReused != tt.wantReused {
+				t.Errorf("Timings.ConnectionReused = %v, want %v", timings.ConnectionReused, tt.wantReused)
+			}
+			if gotConnect := timings.Connect > 0 && timings.TLSHandshake > 0; gotConnect == tt.wantReused {
+				t.Errorf("Timings Connect = %v TLSHandshake = %v, want connected %v",
+					timings.Connect, timings.TLSHandshake, !tt.wantReused)
+			}
+			if timings.RemoteAddr != server.Listener.Addr().String() {
+				t.Errorf("Timings.RemoteAddr = %v, want %v", timings.RemoteAddr, server.Listener.Addr())
+			}
+			if timings.Wait < 20*time.Millisecond || timings.Total < timings.Wait+timings.Transfer {
+				t.Errorf("Timings Wait = %v Transfer = %v Total = %v", timings.Wait, timings.Transfer, timings.Total)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(hooked) != i+1 || hooked[i] != timings {
+				t.Errorf("TimingsHook() timings = %+v, want %+v", hooked, timings)
+			}
+		})
+	}
+}
+
+func TestWithTimingsHook_error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	var hookErr error
+	client, _ := newTestClient(server, WithTimingsHook(func(httpRequest *http.Request, timings Timings, err error) {
+		hookErr = err
+	}))
+	req, _ := NewRequest(http.MethodGet, "/api/test")
+	if _, err := client.Do(req); err == nil {
+		t.Errorf("Do() error = %v, wantErr true", err)
+	}
+	if hookErr == nil {
+		t.Errorf("TimingsHook() err = %v, wantErr true", hookErr)
+	}
+}
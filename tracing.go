@@ -0,0 +1,272 @@
+package request
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Sampled    bool
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+type spanContextKey struct{}
+
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+type SpanStatus int
+
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOK
+	SpanStatusError
+)
+
+// Span is the part of a span the client needs, adapt the spans of your tracer
+// to it.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value any)
+	SetStatus(status SpanStatus, description string)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts a span as a child of the span in ctx, the returned context
+// carries the new span.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Propagator writes a span context into the headers of an outgoing request and
+// reads it from the headers of an incoming one.
+type Propagator interface {
+	Inject(sc SpanContext, header http.Header)
+	Extract(header http.Header) (SpanContext, bool)
+}
+
+const (
+	traceparentHeader = "Traceparent"
+	tracestateHeader  = "Tracestate"
+	b3Header          = "B3"
+	b3TraceIDHeader   = "X-B3-Traceid"
+	b3SpanIDHeader    = "X-B3-Spanid"
+	b3SampledHeader   = "X-B3-Sampled"
+)
+
+// TraceContextPropagator propagates the W3C Trace Context traceparent and
+// tracestate headers.
+type TraceContextPropagator struct{}
+
+func (TraceContextPropagator) Inject(sc SpanContext, header http.Header) {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	header.Set(traceparentHeader, "00-"+hex.EncodeToString(sc.TraceID[:])+"-"+hex.EncodeToString(sc.SpanID[:])+"-"+flags)
+	if sc.TraceState != "" {
+		header.Set(tracestateHeader, sc.TraceState)
+	} else {
+		header.Del(tracestateHeader)
+	}
+}
+
+func (TraceContextPropagator) Extract(header http.Header) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header.Get(traceparentHeader)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHexID(sc.TraceID[:], parts[1]) || !decodeHexID(sc.SpanID[:], parts[2]) ||
+		!decodeHexID(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	sc.TraceState = strings.Join(header.Values(tracestateHeader), ",")
+	return sc, sc.IsValid()
+}
+
+// B3Propagator propagates the Zipkin B3 headers, as one b3 header when
+// SingleHeader is set or as X-B3-* headers otherwise.
+type B3Propagator struct {
+	SingleHeader bool
+}
+
+func (p B3Propagator) Inject(sc SpanContext, header http.Header) {
+	sampled := "0"
+	if sc.Sampled {
+		sampled = "1"
+	}
+	traceID, spanID := hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:])
+	if p.SingleHeader {
+		header.Set(b3Header, traceID+"-"+spanID+"-"+sampled)
+		return
+	}
+	header.Set(b3TraceIDHeader, traceID)
+	header.Set(b3SpanIDHeader, spanID)
+	header.Set(b3SampledHeader, sampled)
+}
+
+func (p B3Propagator) Extract(header http.Header) (SpanContext, bool) {
+	traceID, spanID, sampled := header.Get(b3TraceIDHeader), header.Get(b3SpanIDHeader), header.Get(b3SampledHeader)
+	if single := header.Get(b3Header); single != "" {
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			return SpanContext{}, false
+		}
+		traceID, spanID, sampled = parts[0], parts[1], ""
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+	}
+
+	var sc SpanContext
+	// a 64 bit trace id is left padded to 128 bits
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !decodeHexID(sc.TraceID[:], traceID) || !decodeHexID(sc.SpanID[:], spanID) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = sampled == "1" || sampled == "d" || sampled == "true"
+	return sc, sc.IsValid()
+}
+
+func decodeHexID(dst []byte, value string) bool {
+	if len(value) != hex.EncodedLen(len(dst)) || strings.ToLower(value) != value {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(value))
+	return err == nil
+}
+
+type tracing struct {
+	tracer      Tracer
+	propagators []Propagator
+}
+
+func (c *Client) tracing() *tracing {
+	if c.trace == nil {
+		c.trace = &tracing{}
+	}
+	return c.trace
+}
+
+// WithTracer starts a span around every call of Do and a child span around
+// every round trip of it, which includes retries, hedges and redirects.
+func WithTracer(tracer Tracer) ClientOption {
+	return func(c *Client) error {
+		c.tracing().tracer = tracer
+		return nil
+	}
+}
+
+// WithTracePropagation writes the span context of every round trip into the
+// request headers with each of propagators. Without a tracer the span context
+// of the request context is propagated.
+func WithTracePropagation(propagators ...Propagator) ClientOption {
+	return func(c *Client) error {
+		c.tracing().propagators = append(c.tracing().propagators, propagators...)
+		return nil
+	}
+}
+
+func (t *tracing) do(httpRequest *http.Request, send func(*http.Request) (*Response, error)) (*Response, error) {
+	if t.tracer == nil {
+		return send(httpRequest)
+	}
+
+	ctx, span := t.tracer.Start(httpRequest.Context(), "HTTP "+httpRequest.Method)
+	defer span.End()
+
+	span.SetAttribute("http.request.method", httpRequest.Method)
+	span.SetAttribute("url.full", httpRequest.URL.String())
+
+	resp, err := send(httpRequest.WithContext(ctx))
+	if resp != nil && resp.CacheStatus != "" {
+		span.SetAttribute("http.cache_status", string(resp.CacheStatus))
+	}
+	endSpan(span, resp, err)
+	return resp, err
+}
+
+func endSpan(span Span, resp *Response, err error) {
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(SpanStatusError, err.Error())
+	case resp.StatusCode >= http.StatusBadRequest:
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+		span.SetStatus(SpanStatusError, http.StatusText(resp.StatusCode))
+	default:
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+	}
+}
+
+type tracingTransport struct {
+	tracing *tracing
+	next    http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(httpRequest *http.Request) (*http.Response, error) {
+	ctx := httpRequest.Context()
+	var span Span
+	if t.tracing.tracer != nil {
+		ctx, span = t.tracing.tracer.Start(ctx, "HTTP "+httpRequest.Method+" round trip")
+		span.SetAttribute("http.request.method", httpRequest.Method)
+		span.SetAttribute("url.full", httpRequest.URL.String())
+		span.SetAttribute("server.address", httpRequest.URL.Host)
+		if httpRequest.Response != nil {
+			span.SetAttribute("http.redirect", true)
+		}
+	}
+
+	var sc SpanContext
+	var ok bool
+	if span != nil {
+		sc, ok = span.SpanContext(), span.SpanContext().IsValid()
+	} else {
+		sc, ok = SpanContextFromContext(ctx)
+	}
+	if ok && len(t.tracing.propagators) != 0 {
+		httpRequest = httpRequest.Clone(ctx)
+		for _, propagator := range t.tracing.propagators {
+			propagator.Inject(sc, httpRequest.Header)
+		}
+	} else if span != nil {
+		httpRequest = httpRequest.WithContext(ctx)
+	}
+
+	httpResponse, err := t.next.RoundTrip(httpRequest)
+	if span != nil {
+		var resp *Response
+		if httpResponse != nil {
+			resp = &Response{StatusCode: httpResponse.StatusCode}
+		}
+		endSpan(span, resp, err)
+		span.End()
+	}
+	return httpResponse, err
+}
+
+func (t *tracingTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
@@ -0,0 +1,233 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type testSpan struct {
+	name       string
+	parent     SpanContext
+	sc         SpanContext
+	attributes map[string]any
+	status     SpanStatus
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SpanContext() SpanContext { return s.sc }
+
+func (s *testSpan) SetAttribute(key string, value any) { s.attributes[key] = value }
+
+func (s *testSpan) SetStatus(status SpanStatus, description string) { s.status = status }
+
+func (s *testSpan) RecordError(err error) { s.err = err }
+
+func (s *testSpan) End() { s.ended = true }
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, _ := SpanContextFromContext(ctx)
+	span := &testSpan{name: name, parent: parent, attributes: map[string]any{}}
+	span.sc = SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if !parent.IsValid() {
+		span.sc.TraceID = [16]byte{1}
+	}
+	span.sc.SpanID = [8]byte{byte(len(t.spans) + 1)}
+	t.spans = append(t.spans, span)
+	return ContextWithSpanContext(ctx, span.sc), span
+}
+
+func TestPropagator(t *testing.T) {
+	sc := SpanContext{
+		TraceID:    [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Sampled:    true,
+		TraceState: "congo=t61rcWkgMzE",
+	}
+	tests := []struct {
+		name       string
+		propagator Propagator
+		want       http.Header
+		wantSC     SpanContext
+	}{
+		{
+			name:       "w3c trace context",
+			propagator: TraceContextPropagator{},
+			want: http.Header{
+				"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+				"Tracestate":  {"congo=t61rcWkgMzE"},
+			},
+			wantSC: sc,
+		},
+		{
+			name:       "b3 multiple headers",
+			propagator: B3Propagator{},
+			want: http.Header{
+				"X-B3-Traceid": {"4bf92f3577b34da6a3ce929d0e0e4736"},
+				"X-B3-Spanid":  {"00f067aa0ba902b7"},
+				"X-B3-Sampled": {"1"},
+			},
+			wantSC: SpanContext{TraceID: sc.TraceID, SpanID: sc.SpanID, Sampled: true},
+		},
+		{
+			name:       "b3 single header",
+			propagator: B3Propagator{SingleHeader: true},
+			want:       http.Header{"B3": {"4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"}},
+			wantSC:     SpanContext{TraceID: sc.TraceID, SpanID: sc.SpanID, Sampled: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			tt.propagator.Inject(sc, header)
+			if !reflect.DeepEqual(header, tt.want) {
+				t.Errorf("Inject() = %v, want %v", header, tt.want)
+			}
+
+			got, ok := tt.propagator.Extract(header)
+			if !ok || !reflect.DeepEqual(got, tt.wantSC) {
+				t.Errorf("Extract() = %+v, %v, want %+v", got, ok, tt.wantSC)
+			}
+		})
+	}
+}
+
+func TestPropagator_Extract_invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		propagator Propagator
+		header     http.Header
+	}{
+		{name: "missing traceparent", propagator: TraceContextPropagator{}, header: http.Header{}},
+		{
+			name:       "invalid traceparent version",
+			propagator: TraceContextPropagator{},
+			header:     http.Header{"Traceparent": {"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+		},
+		{
+			name:       "zero trace id",
+			propagator: TraceContextPropagator{},
+			header:     http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}},
+		},
+		{
+			name:       "uppercase span id",
+			propagator: TraceContextPropagator{},
+			header:     http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00F067AA0BA902B7-01"}},
+		},
+		{name: "invalid b3", propagator: B3Propagator{}, header: http.Header{"B3": {"0"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := tt.propagator.Extract(tt.header); ok {
+				t.Errorf("Extract() = %+v, want invalid", got)
+			}
+		})
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	var mu sync.Mutex
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		mu.Unlock()
+		if r.URL.Path == "/api/old" {
+			http.Redirect(w, r, "/api/new", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tracer := &testTracer{}
+	client, err := newTestClient(server, WithTracer(tracer), WithTracePropagation(TraceContextPropagator{}))
+	if err != nil {
+		t.Errorf("NewClient() error = %v", err)
+		return
+	}
+
+	req, _ := NewRequest(http.MethodGet, "/api/old")
+	if _, err := client.Do(req); err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Errorf("Do() spans = %v, want 3", len(tracer.spans))
+		return
+	}
+	do, first, redirect := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if do.name != "HTTP GET" || do.status != SpanStatusError || !do.ended {
+		t.Errorf("Do() span = %+v", do)
+	}
+	for i, span := range []*testSpan{first, redirect} {
+		if span.parent != do.sc || !span.ended {
+			t.Errorf("Do() round trip span = %+v, want child of %+v", span, do.sc)
+		}
+		want := "00-01000000000000000000000000000000-0" + string(rune('2'+i)) + "00000000000000-01"
+		if traceparents[i] != want {
+			t.Errorf("Do() traceparent = %v, want %v", traceparents[i], want)
+		}
+	}
+	if first.attributes["http.response.status_code"] != http.StatusFound || first.status != SpanStatusUnset {
+		t.Errorf("Do() first round trip span = %+v", first)
+	}
+	if redirect.attributes["http.redirect"] != true || redirect.status != SpanStatusError {
+		t.Errorf("Do() redirect round trip span = %+v", redirect)
+	}
+}
+
+func TestWithTracer_error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	tracer := &testTracer{}
+	client, _ := newTestClient(server, WithTracer(tracer))
+	req, _ := NewRequest(http.MethodGet, "/api/test")
+	if _, err := client.Do(req); err == nil {
+		t.Errorf("Do() error = %v, wantErr true", err)
+		return
+	}
+	if len(tracer.spans) != 2 {
+		t.Errorf("Do() spans = %v, want 2", len(tracer.spans))
+	}
+	for _, span := range tracer.spans {
+		if span.status != SpanStatusError || span.err == nil {
+			t.Errorf("Do() span = %+v, want error", span)
+		}
+	}
+}
+
+func TestWithTracePropagation(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server, WithTracePropagation(B3Propagator{}))
+	sc := SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}}
+	req, _ := NewRequest(http.MethodGet, "/api/test", WithContext(ContextWithSpanContext(context.Background(), sc)))
+	if _, err := client.Do(req); err != nil {
+		t.Errorf("Do() error = %v", err)
+		return
+	}
+
+	got, ok := B3Propagator{}.Extract(header)
+	if !ok || got != sc {
+		t.Errorf("Do() propagated = %+v, want %+v", got, sc)
+	}
+}